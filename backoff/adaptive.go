@@ -0,0 +1,104 @@
+package backoff
+
+import (
+	"sync"
+	"time"
+)
+
+// AdaptivePolicy is a backoff policy that adapts its delay to the feedback of all calls sharing it: the delay is
+// multiplied by a factor on every failure and decreased by a fixed amount on every success, which corresponds to an
+// additive increase/multiplicative decrease (AIMD) of the request rate. Unlike other policies, New() returns the
+// policy itself, so the learned delay is shared by all invocations of Retry. Retry reports the outcome of every
+// attempt automatically. It is safe for concurrent use.
+type AdaptivePolicy struct {
+	min      time.Duration
+	max      time.Duration
+	factor   float64
+	decrease time.Duration
+
+	mu          sync.Mutex
+	current     time.Duration
+	isThrottled func(err error) bool
+}
+
+var _ Policy = (*AdaptivePolicy)(nil)
+
+// NewAdaptivePolicy creates an AdaptivePolicy whose delay starts at min, is multiplied by factor on every failure up
+// to max and is decreased by decrease on every success down to min. The minimum delay should be positive.
+func NewAdaptivePolicy(min, max time.Duration, factor float64, decrease time.Duration) *AdaptivePolicy {
+	return &AdaptivePolicy{
+		min:      min,
+		max:      max,
+		factor:   factor,
+		decrease: decrease,
+		current:  min,
+	}
+}
+
+// WithThrottleCheck modifies the policy to only increase the delay for errors for which isThrottled returns true,
+// e.g. errors caused by HTTP status 429. Other errors do not change the delay. It should be called before the policy
+// is shared, so that all failures are checked, but it is safe to call concurrently with Retry.
+func (p *AdaptivePolicy) WithThrottleCheck(isThrottled func(err error) bool) *AdaptivePolicy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.isThrottled = isThrottled
+	return p
+}
+
+// With wraps the policy into a BackOff and applies the given options.
+func (p *AdaptivePolicy) With(opts ...Option) *BackOff {
+	return NewBackOff(p).With(opts...)
+}
+
+// NextBackOff returns the current delay.
+func (p *AdaptivePolicy) NextBackOff() time.Duration {
+	return p.Delay()
+}
+
+// New returns the policy itself, so that its state is shared.
+func (p *AdaptivePolicy) New() Policy {
+	return p
+}
+
+// Delay returns the current delay.
+func (p *AdaptivePolicy) Delay() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.current
+}
+
+// Success decreases the delay after a successful call.
+func (p *AdaptivePolicy) Success() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current -= p.decrease
+	if p.current < p.min {
+		p.current = p.min
+	}
+}
+
+// Failure increases the delay after a failed call.
+func (p *AdaptivePolicy) Failure() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = time.Duration(float64(p.current) * p.factor)
+	if p.current > p.max {
+		p.current = p.max
+	}
+	if p.current < p.min {
+		p.current = p.min
+	}
+}
+
+func (p *AdaptivePolicy) feedback(err error) {
+	if err == nil {
+		p.Success()
+		return
+	}
+	p.mu.Lock()
+	isThrottled := p.isThrottled
+	p.mu.Unlock()
+	if isThrottled == nil || isThrottled(err) {
+		p.Failure()
+	}
+}
@@ -0,0 +1,119 @@
+package backoff_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+	"github.com/ireward/wago/backoff/backofftest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptivePolicy(t *testing.T) {
+	p := backoff.NewAdaptivePolicy(time.Second, 10*time.Second, 3, 2*time.Second)
+	assert.Equal(t, time.Second, p.NextBackOff())
+
+	p.Failure()
+	assert.Equal(t, 3*time.Second, p.Delay())
+	p.Failure()
+	assert.Equal(t, 9*time.Second, p.Delay())
+	p.Failure()
+	assert.Equal(t, 10*time.Second, p.Delay())
+
+	p.Success()
+	assert.Equal(t, 8*time.Second, p.Delay())
+	for i := 0; i < 5; i++ {
+		p.Success()
+	}
+	assert.Equal(t, time.Second, p.Delay())
+	assert.Same(t, p, p.New())
+}
+
+func TestAdaptivePolicyRetry(t *testing.T) {
+	p := backoff.NewAdaptivePolicy(time.Second, time.Minute, 2, time.Second)
+	clock := backofftest.NewRecorder(start)
+
+	var count uint
+	err := backoff.Retry(p.With(backoff.MaxRetries(5)), func() error {
+		count++
+		if count <= 3 {
+			return errTest
+		}
+		return nil
+	}, backoff.WithClock(clock))
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second}, clock.Delays())
+	assert.Equal(t, 7*time.Second, p.Delay())
+}
+
+func TestAdaptivePolicyThrottleCheck(t *testing.T) {
+	errThrottled := errors.New("throttled")
+	p := backoff.NewAdaptivePolicy(time.Second, time.Minute, 2, time.Second).
+		WithThrottleCheck(func(err error) bool {
+			return errors.Is(err, errThrottled)
+		})
+	clock := backofftest.NewRecorder(start)
+
+	var count uint
+	err := backoff.Retry(p, func() error {
+		count++
+		switch count {
+		case 1:
+			return errTest
+		case 2:
+			return backoff.RetryAfter(errThrottled, 5*time.Second)
+		case 3:
+			return errThrottled
+		}
+		return nil
+	}, backoff.WithClock(clock))
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{time.Second, 5 * time.Second, 4 * time.Second}, clock.Delays())
+	assert.Equal(t, 3*time.Second, p.Delay())
+}
+
+func TestAdaptivePolicyParallel(t *testing.T) {
+	const parallelism = 10
+
+	p := backoff.NewAdaptivePolicy(time.Millisecond, time.Second, 2, time.Millisecond)
+
+	var wg sync.WaitGroup
+	wg.Add(parallelism)
+	for i := 0; i < parallelism; i++ {
+		go func() {
+			defer wg.Done()
+			var count uint
+			err := backoff.Retry(p, func() error {
+				count++
+				if count <= 2 {
+					return errTest
+				}
+				return nil
+			}, backoff.WithClock(backofftest.NewRecorder(start)))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.GreaterOrEqual(t, p.Delay(), time.Millisecond)
+	assert.LessOrEqual(t, p.Delay(), time.Second)
+}
+
+func TestAdaptivePolicyThrottleCheckConcurrent(t *testing.T) {
+	p := backoff.NewAdaptivePolicy(time.Millisecond, time.Second, 2, time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = backoff.Retry(p.With(backoff.MaxRetries(10)), func() error {
+			return errTest
+		}, backoff.WithClock(backofftest.NewRecorder(start)))
+	}()
+	p.WithThrottleCheck(func(error) bool {
+		return false
+	})
+	<-done
+	assert.GreaterOrEqual(t, p.Delay(), time.Millisecond)
+}
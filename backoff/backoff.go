@@ -1,13 +1,27 @@
 package backoff
 
 import (
+	"context"
 	"errors"
+	"sync"
 	"time"
 )
 
 // Stop indicates that no more retries should be made for use in NextBackOff().
 const Stop time.Duration = -1
 
+// ErrUnacceptableResult is returned by the retry functions if the backoff policy stops while the result of the last
+// attempt was still rejected by a RetryOnResult option.
+var ErrUnacceptableResult = errors.New("backoff: unacceptable result")
+
+// ErrResultType is returned by the retry functions without calling the operation if the type of a RetryOnResult
+// predicate does not match the type of the returned values.
+var ErrResultType = errors.New("backoff: RetryOnResult does not accept the result type")
+
+// ErrAttemptTimeout is the error of an attempt that did not finish within the duration configured with AttemptTimeout.
+// Such attempts are retried.
+var ErrAttemptTimeout = errors.New("backoff: attempt timed out")
+
 // Permanent wraps the given err in a permanent error signaling that the operation should not be retried.
 func Permanent(err error) error {
 	if err == nil {
@@ -18,6 +32,25 @@ func Permanent(err error) error {
 	}
 }
 
+// IsPermanent reports whether err or any error it wraps has been wrapped with Permanent.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// RetryAfter wraps the given err in an error signaling that the operation should be retried after the duration d,
+// e.g. as requested by a server with a Retry-After header. Retry waits for d instead of the duration returned by the
+// backoff policy. Options limiting the duration, such as MaxInterval and Timeout, are still applied to d.
+func RetryAfter(err error, d time.Duration) error {
+	if err == nil {
+		panic("no error specified")
+	}
+	return &retryAfterError{
+		err:   err,
+		delay: d,
+	}
+}
+
 // Policy is a backoff policy for retrying an operation.
 type Policy interface {
 	// NextBackOff returns the duration to wait before retrying the operation,
@@ -52,28 +85,244 @@ func (b *BackOff) With(opts ...Option) *BackOff {
 
 // Retry calls the function f until it does not return error or the backoff policy stops.
 // The function is guaranteed to be run at least once.
-// If the functions returns a permanent error, the operation is not retried, and the wrapped error is returned.
+// If the functions returns a permanent error, the operation is not retried.
 // Retry sleeps the goroutine for the duration returned by BackOff after a failed operation returns.
-func Retry(p Policy, f func() error) (err error) {
+// If the operation did not succeed, a *RetryError describing the attempts is returned.
+func Retry(p Policy, f func() error, opts ...RetryOption) error {
+	return RetryContext(context.Background(), p, func(context.Context) error {
+		return f()
+	}, opts...)
+}
+
+// RetryContext calls the function f until it does not return error, the backoff policy stops or the context is done.
+// The function is guaranteed to be run at least once and receives ctx on every attempt.
+// If the functions returns a permanent error, the operation is not retried.
+// If the context is done while waiting for the next attempt, the wait is interrupted and the returned *RetryError
+// wraps both ctx.Err() and the error of the last attempt.
+func RetryContext(ctx context.Context, p Policy, f func(ctx context.Context) error, opts ...RetryOption) error {
+	_, err := retry(ctx, p, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, f(ctx)
+	}, opts)
+	return err
+}
+
+// RetryWithData works like Retry but for functions returning a value.
+// The value of the last attempt is returned together with its error.
+func RetryWithData[T any](p Policy, f func() (T, error), opts ...RetryOption) (T, error) {
+	return retry(context.Background(), p, func(context.Context) (T, error) {
+		return f()
+	}, opts)
+}
+
+// RetryWithDataContext works like RetryContext but for functions returning a value.
+// The value of the last attempt is returned together with its error.
+func RetryWithDataContext[T any](ctx context.Context, p Policy, f func(ctx context.Context) (T, error),
+	opts ...RetryOption) (T, error) {
+	return retry(ctx, p, f, opts)
+}
+
+func retry[T any](ctx context.Context, p Policy, f func(ctx context.Context) (T, error),
+	opts []RetryOption) (v T, err error) {
+	o := newRetryOptions(opts)
+	if err := checkResultType[T](o); err != nil {
+		return v, err
+	}
 	p = p.New()
+	setClock(p, o.clock)
+
+	start := o.clock.Now()
+	var (
+		attempt int
+		errs    []error
+	)
+	giveUp := func(reason StopReason, err error) error {
+		return newRetryError(reason, err, errs, attempt, o.clock.Now().Sub(start))
+	}
+
 	for {
-		if err = f(); err == nil {
+		v, err = runAttempt(ctx, o, f)
+		if err == nil && o.retryResult != nil && o.retryResult(v) {
+			err = ErrUnacceptableResult
+		}
+		feedback(p, err)
+		if err == nil {
 			return
 		}
+		attempt++
+		errs = appendAttemptError(errs, err)
 
 		var permanent *permanentError
 		if errors.As(err, &permanent) {
-			return permanent.Unwrap()
+			return v, giveUp(StopPermanent, permanent.Unwrap())
 		}
 
 		duration := p.NextBackOff()
 		if duration == Stop {
-			break
+			return v, giveUp(stopReason(p), err)
+		}
+
+		var retryAfter *retryAfterError
+		if errors.As(err, &retryAfter) {
+			if duration = limit(p, retryAfter.delay); duration == Stop {
+				return v, giveUp(stopReason(p), err)
+			}
+		}
+
+		for _, notify := range o.notify {
+			notify(attempt, err, duration)
 		}
 
-		time.Sleep(duration)
+		if ctxErr := sleep(ctx, o.clock, duration); ctxErr != nil {
+			return v, giveUp(StopContext, &contextError{ctxErr: ctxErr, err: err})
+		}
+		o.discardValue(v)
 	}
-	return err
+}
+
+// runAttempt calls f once after waiting for the Limiter configured with RateLimit. If the wait fails, its error is
+// returned as the error of the attempt. If an AttemptTimeout is configured, f is called in a separate goroutine with a
+// context that is cancelled after the timeout. If f does not return in time, it is abandoned and ErrAttemptTimeout is
+// returned. The context of a successful attempt is not cancelled, so that values tied to it, such as response bodies,
+// remain usable.
+func runAttempt[T any](ctx context.Context, o *retryOptions, f func(ctx context.Context) (T, error)) (T, error) {
+	if o.limiter != nil {
+		if err := o.limiter.Wait(ctx); err != nil {
+			var zero T
+			return zero, err
+		}
+	}
+	if o.attemptTimeout <= 0 {
+		return f(ctx)
+	}
+
+	attemptCtx := newAttemptContext(ctx, o.clock.Now().Add(o.attemptTimeout))
+	timer := o.clock.NewTimer(o.attemptTimeout)
+	done := make(chan attemptResult[T], 1)
+	go func() {
+		v, err := f(attemptCtx)
+		done <- attemptResult[T]{v: v, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		timer.Stop()
+		if r.err != nil {
+			attemptCtx.cancel(context.Canceled)
+		}
+		return r.v, r.err
+	case <-timer.C():
+		attemptCtx.cancel(context.DeadlineExceeded)
+	case <-ctx.Done():
+		timer.Stop()
+		attemptCtx.cancel(ctx.Err())
+	}
+	go func() {
+		if r := <-done; r.err == nil {
+			o.discardValue(r.v)
+		}
+	}()
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	return zero, ErrAttemptTimeout
+}
+
+// attemptResult is the outcome of an attempt running in a separate goroutine.
+type attemptResult[T any] struct {
+	v   T
+	err error
+}
+
+// attemptContext is the context of an attempt with a timeout. Unlike a context derived with context.WithTimeout, it is
+// only done when it is cancelled explicitly, and it is not registered with its parent: runAttempt cancels it if the
+// parent is done while the attempt is running. So the context of a successful attempt stays valid without keeping
+// resources alive until the parent is done.
+type attemptContext struct {
+	parent   context.Context
+	deadline time.Time
+	done     chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+func newAttemptContext(parent context.Context, deadline time.Time) *attemptContext {
+	c := &attemptContext{
+		parent:   parent,
+		deadline: deadline,
+		done:     make(chan struct{}),
+	}
+	if d, ok := parent.Deadline(); ok && d.Before(deadline) {
+		c.deadline = d
+	}
+	return c
+}
+
+func (c *attemptContext) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func (c *attemptContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *attemptContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *attemptContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+// cancel makes the context done with the given error, unless it is already done.
+func (c *attemptContext) cancel(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+		close(c.done)
+	}
+}
+
+// sleep blocks for the given duration or until the context is done, in which case the context error is returned.
+func sleep(ctx context.Context, clock Clock, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	timer := clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
+
+// contextError signals that the retries were interrupted by the context.
+// It unwraps to the context error and additionally matches the error of the last attempt.
+type contextError struct {
+	ctxErr error
+	err    error
+}
+
+func (e *contextError) Error() string {
+	return e.ctxErr.Error() + ": " + e.err.Error()
+}
+
+func (e *contextError) Unwrap() error {
+	return e.ctxErr
+}
+
+func (e *contextError) Is(target error) bool {
+	return errors.Is(e.err, target)
+}
+
+func (e *contextError) As(target interface{}) bool {
+	return errors.As(e.err, target)
 }
 
 // permanentError signals that the operation should not be retried.
@@ -89,10 +338,25 @@ func (e *permanentError) Unwrap() error {
 	return e.err
 }
 
+// retryAfterError signals that the operation should be retried after the given delay.
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryAfterError) Unwrap() error {
+	return e.err
+}
+
 type zeroPolicy struct{}
 
 func (zeroPolicy) NextBackOff() time.Duration { return 0 }
 func (zeroPolicy) New() Policy                { return zeroPolicy{} }
+func (zeroPolicy) spec() (string, bool)       { return "zero", true }
 
 var zeroBackOffInstance = NewBackOff(zeroPolicy{})
 
@@ -115,6 +379,10 @@ func ConstantBackOff(d time.Duration) *BackOff {
 func (b *constantPolicy) NextBackOff() time.Duration { return b.Interval }
 func (b *constantPolicy) New() Policy                { return b }
 
+func (b *constantPolicy) spec() (string, bool) {
+	return "constant(" + b.Interval.String() + ")", true
+}
+
 // ExponentialBackOff returns a backoff policy that increases the backoff period for each retry attempt using a
 // function that grows exponentially.
 // After each call of NextBackOff() the interval is multiplied by the provided factor starting with initialInterval.
@@ -146,6 +414,10 @@ func (b *exponentialPolicy) New() Policy {
 	}
 }
 
+func (b *exponentialPolicy) spec() (string, bool) {
+	return "exponential(initial=" + b.initialInterval.String() + ",factor=" + formatFloat(b.factor) + ")", true
+}
+
 // Increments the current interval by multiplying it with the multiplier.
 func (b *exponentialPolicy) incrementCurrentInterval() {
 	b.currentInterval = time.Duration(float64(b.currentInterval) * b.factor)
@@ -1,16 +1,23 @@
-package backoff
+package backoff_test
 
 import (
+	"context"
 	"errors"
 	"math"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/ireward/wago/backoff"
+	"github.com/ireward/wago/backoff/backofftest"
+
 	"github.com/stretchr/testify/assert"
 )
 
-var errTest = errors.New("test")
+var (
+	errTest = errors.New("test")
+	start   = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+)
 
 const (
 	intervalDelta = 100 * time.Millisecond
@@ -22,24 +29,196 @@ func assertInterval(t assert.TestingT, expected time.Duration, actual time.Durat
 		assert.LessOrEqual(t, actual.Microseconds(), (expected+intervalDelta).Microseconds())
 }
 
+func assertRetryError(t *testing.T, err error, reason backoff.StopReason) *backoff.RetryError {
+	var retryErr *backoff.RetryError
+	if assert.ErrorAs(t, err, &retryErr) {
+		assert.Equal(t, reason, retryErr.Reason)
+		return retryErr
+	}
+	return &backoff.RetryError{}
+}
+
 func TestNoError(t *testing.T) {
-	err := Retry(ZeroBackOff(), func() error {
+	err := backoff.Retry(backoff.ZeroBackOff(), func() error {
 		return nil
 	})
 	assert.NoError(t, err)
 }
 
 func TestPermanentError(t *testing.T) {
-	err := Retry(ZeroBackOff(), func() error {
-		return Permanent(errTest)
+	err := backoff.Retry(backoff.ZeroBackOff(), func() error {
+		return backoff.Permanent(errTest)
+	})
+	assert.Equal(t, errTest, assertRetryError(t, err, backoff.StopPermanent).Err)
+}
+
+func TestIsPermanent(t *testing.T) {
+	assert.True(t, backoff.IsPermanent(backoff.Permanent(errTest)))
+	assert.True(t, backoff.IsPermanent(backoff.RetryAfter(backoff.Permanent(errTest), time.Second)))
+	assert.False(t, backoff.IsPermanent(errTest))
+	assert.False(t, backoff.IsPermanent(nil))
+}
+
+func TestRetryContext(t *testing.T) {
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, true)
+
+	var count uint
+	err := backoff.RetryContext(ctx, backoff.ZeroBackOff(), func(actx context.Context) error {
+		assert.Equal(t, ctx, actx)
+		if count >= retryCount {
+			return nil
+		}
+		count++
+		return errTest
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, retryCount, count)
+}
+
+func TestRetryContextCancel(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+
+	p := backoff.ConstantBackOff(time.Hour)
+	clock := backofftest.NewFakeClock(start)
+
+	go func() {
+		clock.BlockUntil(1)
+		ctxCancel()
+	}()
+
+	var count uint
+	err := backoff.RetryContext(ctx, p, func(context.Context) error {
+		count++
+		return errTest
+	}, backoff.WithClock(clock))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.True(t, errors.Is(err, errTest))
+	assert.EqualValues(t, 1, count)
+	assert.Equal(t, start, clock.Now())
+	assertRetryError(t, err, backoff.StopContext)
+}
+
+func TestRetryContextPermanentError(t *testing.T) {
+	err := backoff.RetryContext(context.Background(), backoff.ZeroBackOff(), func(context.Context) error {
+		return backoff.Permanent(errTest)
+	})
+	assert.Equal(t, errTest, assertRetryError(t, err, backoff.StopPermanent).Err)
+}
+
+func TestRetryWithData(t *testing.T) {
+	var count uint
+	v, err := backoff.RetryWithData(backoff.ZeroBackOff(), func() (uint, error) {
+		if count >= retryCount {
+			return count, nil
+		}
+		count++
+		return 0, errTest
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, retryCount, v)
+}
+
+func TestRetryWithDataPermanentError(t *testing.T) {
+	v, err := backoff.RetryWithData(backoff.ZeroBackOff(), func() (int, error) {
+		return 1, backoff.Permanent(errTest)
 	})
-	assert.EqualError(t, err, errTest.Error())
+	assert.Equal(t, errTest, assertRetryError(t, err, backoff.StopPermanent).Err)
+	assert.Equal(t, 1, v)
+}
+
+func TestRetryAfter(t *testing.T) {
+	const delay = 3 * time.Second
+
+	clock := backofftest.NewRecorder(start)
+
+	var count uint
+	err := backoff.Retry(backoff.ConstantBackOff(time.Second), func() error {
+		count++
+		switch count {
+		case 1:
+			return backoff.RetryAfter(errTest, delay)
+		case 2:
+			return errTest
+		}
+		return nil
+	}, backoff.WithClock(clock))
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{delay, time.Second}, clock.Delays())
+}
+
+func TestRetryAfterLimited(t *testing.T) {
+	clock := backofftest.NewRecorder(start)
+
+	p := backoff.ConstantBackOff(time.Second).With(
+		backoff.MaxInterval(5*time.Second),
+		backoff.Timeout(start.Add(7*time.Second)),
+		backoff.MaxRetries(3),
+	)
+
+	var count uint
+	err := backoff.Retry(p, func() error {
+		count++
+		return backoff.RetryAfter(errTest, time.Minute)
+	}, backoff.WithClock(clock))
+	assert.True(t, errors.Is(err, errTest))
+	assert.EqualValues(t, 3, count)
+	assert.Equal(t, []time.Duration{5 * time.Second, 2 * time.Second}, clock.Delays())
 }
 
+func TestRetryError(t *testing.T) {
+	errFirst := errors.New("first")
+	clock := backofftest.NewRecorder(start)
+
+	var count uint
+	err := backoff.Retry(backoff.ConstantBackOff(time.Second).With(backoff.MaxRetries(2)), func() error {
+		count++
+		if count == 1 {
+			return errFirst
+		}
+		return errTest
+	}, backoff.WithClock(clock))
+	retryErr := assertRetryError(t, err, backoff.StopMaxRetries)
+	assert.Equal(t, 3, retryErr.Attempts)
+	assert.Equal(t, 2*time.Second, retryErr.Elapsed)
+	assert.Equal(t, []error{errFirst, errTest, errTest}, retryErr.Errors)
+	assert.Equal(t, errTest, retryErr.Err)
+	assert.True(t, errors.Is(err, errFirst))
+	assert.True(t, errors.Is(err, errTest))
+	assert.EqualError(t, err, "backoff: giving up after 3 attempts in 2s (max retries reached): test")
+}
+
+func TestRetryErrorReason(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	ctxCancel()
+
+	tests := map[backoff.StopReason]backoff.Policy{
+		backoff.StopPolicy:     &stopPolicy{},
+		backoff.StopMaxRetries: backoff.ZeroBackOff().With(backoff.MaxRetries(1), backoff.Jitter(0.5)),
+		backoff.StopTimeout:    backoff.ZeroBackOff().With(backoff.Timeout(start), backoff.MaxRetries(1)),
+		backoff.StopCancel:     backoff.ZeroBackOff().With(backoff.Cancel(ctx), backoff.MaxInterval(time.Second)),
+		backoff.StopBudget:     backoff.ZeroBackOff().With(backoff.Budget(backoff.NewRetryBudget(1, 0))),
+	}
+	for reason, p := range tests {
+		t.Run(reason.String(), func(t *testing.T) {
+			err := backoff.Retry(p, func() error {
+				return errTest
+			}, backoff.WithClock(backofftest.NewRecorder(start)))
+			assertRetryError(t, err, reason)
+		})
+	}
+}
+
+// stopPolicy is a backoff policy which stops immediately.
+type stopPolicy struct{}
+
+func (p *stopPolicy) NextBackOff() time.Duration { return backoff.Stop }
+func (p *stopPolicy) New() backoff.Policy        { return p }
+
 func TestZeroBackOff(t *testing.T) {
 	var count uint
 	last := time.Now()
-	err := Retry(ZeroBackOff(), func() error {
+	err := backoff.Retry(backoff.ZeroBackOff(), func() error {
 		now := time.Now()
 		assertInterval(t, 0, now.Sub(last))
 		last = now
@@ -56,16 +235,17 @@ func TestZeroBackOff(t *testing.T) {
 func TestConstantBackOff(t *testing.T) {
 	const interval = 2 * intervalDelta
 
-	p := ConstantBackOff(interval)
+	p := backoff.ConstantBackOff(interval)
+	clock := backofftest.NewRecorder(start)
 
 	var (
 		count uint
 		last  time.Time
 	)
-	err := Retry(p, func() error {
-		now := time.Now()
+	err := backoff.Retry(p, func() error {
+		now := clock.Now()
 		if count > 0 {
-			assertInterval(t, interval, now.Sub(last))
+			assert.Equal(t, interval, now.Sub(last))
 		}
 		last = now
 		if count >= retryCount {
@@ -73,7 +253,7 @@ func TestConstantBackOff(t *testing.T) {
 		}
 		count++
 		return errTest
-	})
+	}, backoff.WithClock(clock))
 	assert.NoError(t, err)
 	assert.EqualValues(t, retryCount, count)
 }
@@ -84,17 +264,18 @@ func TestExponentialBackOff(t *testing.T) {
 		factor   = 1.5
 	)
 
-	p := ExponentialBackOff(interval, factor)
+	p := backoff.ExponentialBackOff(interval, factor)
+	clock := backofftest.NewRecorder(start)
 
 	var (
 		count uint
 		last  time.Time
 	)
-	err := Retry(p, func() error {
-		now := time.Now()
+	err := backoff.Retry(p, func() error {
+		now := clock.Now()
 		if count > 0 {
 			expected := time.Duration(float64(interval) * math.Pow(factor, float64(count-1)))
-			assertInterval(t, expected, now.Sub(last))
+			assert.Equal(t, expected, now.Sub(last))
 		}
 		last = now
 		if count >= retryCount {
@@ -102,7 +283,7 @@ func TestExponentialBackOff(t *testing.T) {
 		}
 		count++
 		return errTest
-	})
+	}, backoff.WithClock(clock))
 	assert.NoError(t, err)
 	assert.EqualValues(t, retryCount, count)
 }
@@ -115,19 +296,20 @@ func TestExponentialBackOffParallel(t *testing.T) {
 	)
 
 	var wg sync.WaitGroup
-	p := ExponentialBackOff(interval, factor)
+	p := backoff.ExponentialBackOff(interval, factor)
 
 	test := func() {
 		defer wg.Done()
+		clock := backofftest.NewRecorder(start)
 		var (
 			count uint
 			last  time.Time
 		)
-		err := Retry(p, func() error {
-			now := time.Now()
+		err := backoff.Retry(p, func() error {
+			now := clock.Now()
 			if count > 0 {
 				expected := time.Duration(float64(interval) * math.Pow(factor, float64(count-1)))
-				assertInterval(t, expected, now.Sub(last))
+				assert.Equal(t, expected, now.Sub(last))
 			}
 			last = now
 			if count >= retryCount {
@@ -135,7 +317,7 @@ func TestExponentialBackOffParallel(t *testing.T) {
 			}
 			count++
 			return errTest
-		})
+		}, backoff.WithClock(clock))
 		assert.NoError(t, err)
 		assert.EqualValues(t, retryCount, count)
 	}
@@ -0,0 +1,151 @@
+// Package backofftest provides clocks for testing code using the backoff package without really sleeping.
+package backofftest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+)
+
+// FakeClock is a backoff.Clock whose time only changes when it is advanced manually.
+// It is safe for concurrent use.
+type FakeClock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*fakeTimer
+}
+
+var _ backoff.Clock = (*FakeClock)(nil)
+
+// NewFakeClock creates a FakeClock starting at the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the current time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer creates a timer that fires once the clock has been advanced by at least d.
+func (c *FakeClock) NewTimer(d time.Duration) backoff.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{
+		clock: c,
+		when:  c.now.Add(d),
+		c:     make(chan time.Time, 1),
+	}
+	if d <= 0 {
+		t.c <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	c.cond.Broadcast()
+	return t
+}
+
+// Advance moves the clock forward by d and fires all timers that are due.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	sort.Slice(c.timers, func(i, j int) bool {
+		return c.timers[i].when.Before(c.timers[j].when)
+	})
+	var pending []*fakeTimer
+	for _, t := range c.timers {
+		if t.when.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- c.now
+	}
+	c.timers = pending
+	c.cond.Broadcast()
+}
+
+// BlockUntil blocks until at least n timers are waiting to fire.
+// It allows to synchronize with a goroutine that is about to wait on the clock.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+func (c *FakeClock) stop(t *fakeTimer) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, timer := range c.timers {
+		if timer == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			c.cond.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	when  time.Time
+	c     chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+func (t *fakeTimer) Stop() bool          { return t.clock.stop(t) }
+
+// Recorder is a backoff.Clock that records the durations of all requested timers.
+// Instead of waiting, every timer fires immediately and advances the time of the clock by its duration, so it is not
+// suited for backoff.AttemptTimeout. It is safe for concurrent use.
+type Recorder struct {
+	mu     sync.Mutex
+	now    time.Time
+	delays []time.Duration
+}
+
+var _ backoff.Clock = (*Recorder)(nil)
+
+// NewRecorder creates a Recorder starting at the given time.
+func NewRecorder(now time.Time) *Recorder {
+	return &Recorder{now: now}
+}
+
+// Now returns the current time of the clock.
+func (r *Recorder) Now() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.now
+}
+
+// NewTimer records d, advances the clock by d and returns a timer that has already fired.
+func (r *Recorder) NewTimer(d time.Duration) backoff.Timer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.delays = append(r.delays, d)
+	r.now = r.now.Add(d)
+	t := firedTimer(make(chan time.Time, 1))
+	t <- r.now
+	return t
+}
+
+// Delays returns the durations of all timers requested so far.
+func (r *Recorder) Delays() []time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]time.Duration(nil), r.delays...)
+}
+
+type firedTimer chan time.Time
+
+func (t firedTimer) C() <-chan time.Time { return t }
+func (t firedTimer) Stop() bool          { return false }
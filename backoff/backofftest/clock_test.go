@@ -0,0 +1,62 @@
+package backofftest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeClock(t *testing.T) {
+	c := NewFakeClock(start)
+	assert.Equal(t, start, c.Now())
+
+	t1 := c.NewTimer(time.Second)
+	t2 := c.NewTimer(2 * time.Second)
+	t3 := c.NewTimer(3 * time.Second)
+	c.BlockUntil(3)
+
+	c.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Second), c.Now())
+	assert.Equal(t, start.Add(time.Second), <-t1.C())
+	select {
+	case <-t2.C():
+		assert.FailNow(t, "timer fired prematurely")
+	default:
+	}
+
+	assert.True(t, t3.Stop())
+	assert.False(t, t3.Stop())
+	assert.False(t, t1.Stop())
+
+	c.Advance(5 * time.Second)
+	assert.Equal(t, start.Add(6*time.Second), <-t2.C())
+	select {
+	case <-t3.C():
+		assert.FailNow(t, "stopped timer fired")
+	default:
+	}
+}
+
+func TestFakeClockBlockUntil(t *testing.T) {
+	c := NewFakeClock(start)
+
+	fired := make(chan time.Time)
+	go func() {
+		fired <- <-c.NewTimer(time.Minute).C()
+	}()
+	c.BlockUntil(1)
+	c.Advance(time.Minute)
+	assert.Equal(t, start.Add(time.Minute), <-fired)
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder(start)
+
+	assert.Equal(t, start.Add(time.Second), <-r.NewTimer(time.Second).C())
+	assert.Equal(t, start.Add(3*time.Second), <-r.NewTimer(2*time.Second).C())
+	assert.Equal(t, start.Add(3*time.Second), r.Now())
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, r.Delays())
+}
@@ -0,0 +1,143 @@
+package backoff
+
+import (
+	"sync"
+	"time"
+)
+
+// RetryBudget limits the number of retries across all Retry invocations sharing it, so that retries do not multiply
+// the load on a failing dependency. Every retry consumes a token, and every successful call adds ratio tokens up
+// to the maximum number of tokens. It is safe for concurrent use.
+type RetryBudget struct {
+	mu        sync.Mutex
+	ratio     float64
+	maxTokens float64
+	tokens    float64
+}
+
+// NewRetryBudget creates a RetryBudget which allows ratio retries per successful call, e.g. 0.1 for at most 10 %
+// additional calls, and which stores at most maxRetries retries. The budget starts full.
+func NewRetryBudget(ratio float64, maxRetries int) *RetryBudget {
+	return &RetryBudget{
+		ratio:     ratio,
+		maxTokens: float64(maxRetries),
+		tokens:    float64(maxRetries),
+	}
+}
+
+// Deposit refills the budget after a successful call.
+func (b *RetryBudget) Deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += b.ratio
+	if b.tokens > b.maxTokens {
+		b.tokens = b.maxTokens
+	}
+}
+
+// Withdraw consumes a token for a retry. It returns false if the budget is exhausted.
+func (b *RetryBudget) Withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// available returns the current number of tokens.
+func (b *RetryBudget) available() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens
+}
+
+// Remaining returns the number of retries left in the budget.
+func (b *RetryBudget) Remaining() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return int(b.tokens)
+}
+
+// Budget configures a backoff policy to return Stop if the given retry budget is exhausted. Successful calls made
+// with Retry refill the budget.
+func Budget(budget *RetryBudget) Option {
+	return optionFunc(func(p Policy) Policy {
+		return &budgetOption{
+			delegate: p,
+			budget:   budget,
+		}
+	})
+}
+
+type budgetOption struct {
+	delegate Policy
+	budget   *RetryBudget
+	stopped  bool
+
+	// preview is set for instances created by Schedule. They withdraw from a copy of the tokens, so that previews do
+	// not consume the shared budget.
+	preview bool
+	tokens  float64
+}
+
+func (b *budgetOption) NextBackOff() time.Duration {
+	duration := b.delegate.NextBackOff()
+	if duration == Stop {
+		return Stop
+	}
+	if !b.withdraw() {
+		b.stopped = true
+		return Stop
+	}
+	return duration
+}
+
+func (b *budgetOption) withdraw() bool {
+	if !b.preview {
+		return b.budget.Withdraw()
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *budgetOption) New() Policy {
+	return &budgetOption{
+		delegate: b.delegate.New(),
+		budget:   b.budget,
+	}
+}
+
+func (b *budgetOption) setClock(c Clock) {
+	if _, ok := c.(*virtualClock); ok {
+		b.preview = true
+		b.tokens = b.budget.available()
+	}
+	setClock(b.delegate, c)
+}
+
+func (b *budgetOption) limit(d time.Duration) time.Duration {
+	return limit(b.delegate, d)
+}
+
+func (b *budgetOption) feedback(err error) {
+	if err == nil {
+		b.budget.Deposit()
+	}
+	feedback(b.delegate, err)
+}
+
+func (b *budgetOption) stopReason() StopReason {
+	if b.stopped {
+		return StopBudget
+	}
+	return stopReason(b.delegate)
+}
+
+func (b *budgetOption) spec() (string, bool) {
+	return "", false
+}
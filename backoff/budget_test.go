@@ -0,0 +1,84 @@
+package backoff_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ireward/wago/backoff"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget(t *testing.T) {
+	budget := backoff.NewRetryBudget(0.5, 2)
+	p := backoff.ZeroBackOff().With(backoff.MaxRetries(retryCount), backoff.Budget(budget))
+
+	var count uint
+	err := backoff.Retry(p, func() error {
+		count++
+		return errTest
+	})
+	assert.True(t, errors.Is(err, errTest))
+	assert.EqualValues(t, 3, count)
+	assert.Equal(t, 0, budget.Remaining())
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, backoff.Retry(p, func() error {
+			return nil
+		}))
+	}
+	assert.Equal(t, 1, budget.Remaining())
+
+	count = 0
+	err = backoff.Retry(p, func() error {
+		count++
+		if count > 1 {
+			return nil
+		}
+		return errTest
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+	assert.Equal(t, 0, budget.Remaining())
+}
+
+func TestBudgetMaxRetries(t *testing.T) {
+	budget := backoff.NewRetryBudget(1, 2)
+	for i := 0; i < 5; i++ {
+		budget.Deposit()
+	}
+	assert.Equal(t, 2, budget.Remaining())
+	assert.True(t, budget.Withdraw())
+	assert.True(t, budget.Withdraw())
+	assert.False(t, budget.Withdraw())
+}
+
+func TestBudgetParallel(t *testing.T) {
+	const (
+		retries     = 10
+		parallelism = 20
+	)
+
+	var (
+		wg    sync.WaitGroup
+		count int32
+	)
+	budget := backoff.NewRetryBudget(0.1, retries)
+	p := backoff.ZeroBackOff().With(backoff.Budget(budget), backoff.MaxRetries(retries))
+
+	wg.Add(parallelism)
+	for i := 0; i < parallelism; i++ {
+		go func() {
+			defer wg.Done()
+			err := backoff.Retry(p, func() error {
+				atomic.AddInt32(&count, 1)
+				return errTest
+			})
+			assert.True(t, errors.Is(err, errTest))
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, parallelism+retries, count)
+}
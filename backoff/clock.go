@@ -0,0 +1,62 @@
+package backoff
+
+import (
+	"time"
+)
+
+// Clock provides the current time and timers to the backoff functions.
+// It allows to replace the system clock in tests, see the backofftest package.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTimer creates a new Timer that sends the current time on its channel after at least duration d.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer represents a single event as created by Clock.NewTimer.
+type Timer interface {
+	// C returns the channel on which the time is delivered.
+	C() <-chan time.Time
+
+	// Stop prevents the Timer from firing. It returns false if the timer has already expired or been stopped.
+	Stop() bool
+}
+
+// SystemClock returns a Clock backed by the time package.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                 { return time.Now() }
+func (systemClock) NewTimer(d time.Duration) Timer { return systemTimer{timer: time.NewTimer(d)} }
+
+type systemTimer struct {
+	timer *time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time { return t.timer.C }
+func (t systemTimer) Stop() bool          { return t.timer.Stop() }
+
+// NewWithClock returns a new instance of the backoff policy p using the Clock c, like the instance created by Retry
+// with WithClock. It is useful to drive a policy outside of Retry, e.g. in tests with a fake clock.
+func NewWithClock(p Policy, c Clock) Policy {
+	p = p.New()
+	setClock(p, c)
+	return p
+}
+
+// clockPolicy is implemented by policies and options whose behavior depends on the current time.
+// Retry passes its Clock to the policy instance created with New().
+type clockPolicy interface {
+	setClock(c Clock)
+}
+
+// setClock passes the clock to p if it is a clockPolicy.
+func setClock(p Policy, c Clock) {
+	if cp, ok := p.(clockPolicy); ok {
+		cp.setClock(c)
+	}
+}
@@ -0,0 +1,214 @@
+package backoff
+
+import (
+	"time"
+)
+
+// LinearBackOff returns a backoff policy whose delay starts with initialInterval and grows by increment after each
+// call of NextBackOff().
+func LinearBackOff(initialInterval, increment time.Duration) *BackOff {
+	return NewBackOff(&linearPolicy{
+		initialInterval: initialInterval,
+		increment:       increment,
+		currentInterval: initialInterval,
+	})
+}
+
+type linearPolicy struct {
+	initialInterval time.Duration
+	increment       time.Duration
+	currentInterval time.Duration
+}
+
+func (b *linearPolicy) NextBackOff() time.Duration {
+	defer func() {
+		b.currentInterval += b.increment
+	}()
+	return b.currentInterval
+}
+
+func (b *linearPolicy) New() Policy {
+	return &linearPolicy{
+		initialInterval: b.initialInterval,
+		increment:       b.increment,
+		currentInterval: b.initialInterval,
+	}
+}
+
+func (b *linearPolicy) spec() (string, bool) {
+	return "linear(initial=" + b.initialInterval.String() + ",increment=" + b.increment.String() + ")", true
+}
+
+// FibonacciBackOff returns a backoff policy whose delays follow the Fibonacci sequence multiplied by
+// initialInterval, i.e. 1, 1, 2, 3, 5, 8, ... times initialInterval.
+func FibonacciBackOff(initialInterval time.Duration) *BackOff {
+	return NewBackOff(&fibonacciPolicy{
+		initialInterval: initialInterval,
+		currentInterval: initialInterval,
+	})
+}
+
+type fibonacciPolicy struct {
+	initialInterval  time.Duration
+	previousInterval time.Duration
+	currentInterval  time.Duration
+}
+
+func (b *fibonacciPolicy) NextBackOff() time.Duration {
+	current := b.currentInterval
+	b.previousInterval, b.currentInterval = current, b.previousInterval+current
+	return current
+}
+
+func (b *fibonacciPolicy) New() Policy {
+	return &fibonacciPolicy{
+		initialInterval: b.initialInterval,
+		currentInterval: b.initialInterval,
+	}
+}
+
+func (b *fibonacciPolicy) spec() (string, bool) {
+	return "fibonacci(" + b.initialInterval.String() + ")", true
+}
+
+// Sequence returns a backoff policy that uses the given policies one after another. Each policy is used until it
+// returns Stop, e.g. to retry quickly a few times before backing off exponentially:
+//
+//	Sequence(ConstantBackOff(50*time.Millisecond).With(MaxRetries(3)), ExponentialBackOff(time.Second, 2))
+//
+// The policy stops when the last policy stops.
+func Sequence(policies ...Policy) *BackOff {
+	return NewBackOff(&sequencePolicy{policies: policies})
+}
+
+type sequencePolicy struct {
+	policies []Policy
+	current  int
+}
+
+func (b *sequencePolicy) NextBackOff() time.Duration {
+	for ; b.current < len(b.policies); b.current++ {
+		if duration := b.policies[b.current].NextBackOff(); duration != Stop {
+			return duration
+		}
+	}
+	return Stop
+}
+
+func (b *sequencePolicy) New() Policy {
+	return &sequencePolicy{policies: newPolicies(b.policies)}
+}
+
+func (b *sequencePolicy) setClock(c Clock) {
+	for _, p := range b.policies {
+		setClock(p, c)
+	}
+}
+
+func (b *sequencePolicy) limit(d time.Duration) time.Duration {
+	if b.current >= len(b.policies) {
+		return Stop
+	}
+	return limit(b.policies[b.current], d)
+}
+
+func (b *sequencePolicy) feedback(err error) {
+	if b.current < len(b.policies) {
+		feedback(b.policies[b.current], err)
+	}
+}
+
+func (b *sequencePolicy) stopReason() StopReason {
+	if len(b.policies) == 0 {
+		return StopPolicy
+	}
+	return stopReason(b.policies[len(b.policies)-1])
+}
+
+// Min returns a backoff policy that returns the shortest of the durations returned by the given policies.
+// The policy stops as soon as one of the policies stops.
+func Min(policies ...Policy) *BackOff {
+	return NewBackOff(&combinedPolicy{
+		policies: policies,
+		combine: func(a, b time.Duration) bool {
+			return a < b
+		},
+	})
+}
+
+// Max returns a backoff policy that returns the longest of the durations returned by the given policies.
+// The policy stops as soon as one of the policies stops.
+func Max(policies ...Policy) *BackOff {
+	return NewBackOff(&combinedPolicy{
+		policies: policies,
+		combine: func(a, b time.Duration) bool {
+			return a > b
+		},
+	})
+}
+
+// combinedPolicy returns the duration of the policies which is preferred by the combine function.
+type combinedPolicy struct {
+	policies []Policy
+	combine  func(a, b time.Duration) bool
+	stopped  int
+}
+
+func (b *combinedPolicy) NextBackOff() time.Duration {
+	result := Stop
+	for i, p := range b.policies {
+		duration := p.NextBackOff()
+		if duration == Stop {
+			b.stopped = i
+			return Stop
+		}
+		if result == Stop || b.combine(duration, result) {
+			result = duration
+		}
+	}
+	return result
+}
+
+func (b *combinedPolicy) New() Policy {
+	return &combinedPolicy{
+		policies: newPolicies(b.policies),
+		combine:  b.combine,
+	}
+}
+
+func (b *combinedPolicy) setClock(c Clock) {
+	for _, p := range b.policies {
+		setClock(p, c)
+	}
+}
+
+func (b *combinedPolicy) limit(d time.Duration) time.Duration {
+	for _, p := range b.policies {
+		if d = limit(p, d); d == Stop {
+			return Stop
+		}
+	}
+	return d
+}
+
+func (b *combinedPolicy) feedback(err error) {
+	for _, p := range b.policies {
+		feedback(p, err)
+	}
+}
+
+func (b *combinedPolicy) stopReason() StopReason {
+	if b.stopped >= len(b.policies) {
+		return StopPolicy
+	}
+	return stopReason(b.policies[b.stopped])
+}
+
+// newPolicies creates new instances of all policies.
+func newPolicies(policies []Policy) []Policy {
+	instances := make([]Policy, len(policies))
+	for i, p := range policies {
+		instances[i] = p.New()
+	}
+	return instances
+}
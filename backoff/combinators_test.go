@@ -0,0 +1,99 @@
+package backoff_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+	"github.com/ireward/wago/backoff/backofftest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinearBackOff(t *testing.T) {
+	p := backoff.LinearBackOff(time.Second, 2*time.Second)
+	expected := []time.Duration{time.Second, 3 * time.Second, 5 * time.Second, 7 * time.Second}
+	assert.Equal(t, expected, backoff.Schedule(p, 4))
+	assert.Equal(t, expected, backoff.Schedule(p, 4))
+}
+
+func TestFibonacciBackOff(t *testing.T) {
+	p := backoff.FibonacciBackOff(time.Second)
+	expected := []time.Duration{1, 1, 2, 3, 5, 8, 13}
+	for i := range expected {
+		expected[i] *= time.Second
+	}
+	assert.Equal(t, expected, backoff.Schedule(p, 7))
+	assert.Equal(t, expected, backoff.Schedule(p, 7))
+}
+
+func TestSequence(t *testing.T) {
+	p := backoff.Sequence(
+		backoff.ConstantBackOff(50*time.Millisecond).With(backoff.MaxRetries(3)),
+		backoff.ExponentialBackOff(time.Second, 2).With(backoff.MaxInterval(time.Minute), backoff.MaxRetries(8)),
+	)
+	expected := []time.Duration{
+		50 * time.Millisecond,
+		50 * time.Millisecond,
+		50 * time.Millisecond,
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		16 * time.Second,
+		32 * time.Second,
+		time.Minute,
+		time.Minute,
+	}
+	assert.Equal(t, expected, backoff.Schedule(p, 20))
+	assert.Equal(t, expected, backoff.Schedule(p, 20))
+}
+
+func TestSequenceRetry(t *testing.T) {
+	p := backoff.Sequence(
+		backoff.ConstantBackOff(time.Second).With(backoff.MaxRetries(1)),
+		backoff.ConstantBackOff(time.Minute).With(backoff.MaxRetries(2)),
+	)
+
+	clock := backofftest.NewRecorder(start)
+	err := backoff.Retry(p, func() error {
+		return errTest
+	}, backoff.WithClock(clock))
+	retryErr := assertRetryError(t, err, backoff.StopMaxRetries)
+	assert.Equal(t, 4, retryErr.Attempts)
+	assert.Equal(t, []time.Duration{time.Second, time.Minute, time.Minute}, clock.Delays())
+}
+
+func TestMin(t *testing.T) {
+	p := backoff.Min(
+		backoff.ExponentialBackOff(time.Second, 2),
+		backoff.LinearBackOff(3*time.Second, time.Second).With(backoff.MaxRetries(4)),
+	)
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 6 * time.Second}
+	assert.Equal(t, expected, backoff.Schedule(p, 10))
+	assert.Equal(t, expected, backoff.Schedule(p, 10))
+}
+
+func TestMax(t *testing.T) {
+	p := backoff.Max(
+		backoff.ExponentialBackOff(time.Second, 2).With(backoff.MaxRetries(4)),
+		backoff.LinearBackOff(3*time.Second, time.Second),
+	)
+	expected := []time.Duration{3 * time.Second, 4 * time.Second, 5 * time.Second, 8 * time.Second}
+	assert.Equal(t, expected, backoff.Schedule(p, 10))
+	assert.Equal(t, expected, backoff.Schedule(p, 10))
+}
+
+func TestMaxRetry(t *testing.T) {
+	p := backoff.Max(
+		backoff.ZeroBackOff().With(backoff.MaxElapsedTime(time.Minute)),
+		backoff.ConstantBackOff(25*time.Second),
+	)
+
+	clock := backofftest.NewRecorder(start)
+	err := backoff.Retry(p, func() error {
+		return errTest
+	}, backoff.WithClock(clock))
+	assertRetryError(t, err, backoff.StopTimeout)
+	assert.Equal(t, []time.Duration{25 * time.Second, 25 * time.Second, 25 * time.Second}, clock.Delays())
+}
@@ -0,0 +1,465 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrDisconnected is returned by a ReconnectingConn if the connection is lost. Reads and writes succeed again
+	// once the connection is re-established, but data may have been lost in between.
+	ErrDisconnected = errors.New("backoff: connection lost")
+
+	// ErrBufferFull is returned by a ReconnectingConn in BufferWrites mode if the buffer for writes made while
+	// disconnected is full.
+	ErrBufferFull = errors.New("backoff: write buffer full")
+)
+
+// defaultWriteBufferSize is the size of the write buffer if Dialer.BufferSize is not set.
+const defaultWriteBufferSize = 64 << 10
+
+// ConnState is the state of a ReconnectingConn.
+type ConnState int
+
+const (
+	// ConnConnecting is the state in which a connection is dialed.
+	ConnConnecting ConnState = iota
+	// ConnConnected is the state in which the connection is established.
+	ConnConnected
+	// ConnDisconnected is the state after the connection was lost and before it is dialed again.
+	ConnDisconnected
+	// ConnClosed is the state after the connection was closed, the policy stopped redialing or the connection was lost
+	// without a policy.
+	ConnClosed
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case ConnConnecting:
+		return "connecting"
+	case ConnConnected:
+		return "connected"
+	case ConnDisconnected:
+		return "disconnected"
+	case ConnClosed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+// WriteMode determines how a ReconnectingConn handles writes while it is disconnected.
+type WriteMode int
+
+const (
+	// FailWrites lets writes fail with ErrDisconnected while disconnected.
+	FailWrites WriteMode = iota
+	// BufferWrites buffers writes while disconnected and sends them once the connection is re-established.
+	BufferWrites
+)
+
+// Dialer dials connections that are redialed according to a backoff policy when they are lost.
+type Dialer struct {
+	// Network and Address are passed to DialContext.
+	Network string
+	Address string
+
+	// Policy is the backoff policy used for dialing. If nil, connections are dialed only once and not redialed: a lost
+	// connection moves to ConnClosed, and all further operations return the error with which it was lost.
+	Policy Policy
+
+	// DialContext dials a single connection. If nil, the DialContext method of a zero net.Dialer is used.
+	DialContext func(ctx context.Context, network, address string) (net.Conn, error)
+
+	// WriteMode determines how writes are handled while disconnected.
+	WriteMode WriteMode
+
+	// BufferSize is the maximum number of bytes buffered in BufferWrites mode. If zero, 64 KiB are buffered.
+	BufferSize int
+
+	// OnStateChange is called for every state change of a connection together with the error causing it, if any.
+	// It is called synchronously, but never while the connection is locked.
+	OnStateChange func(state ConnState, err error)
+
+	// Options are passed to Retry for every dial.
+	Options []RetryOption
+}
+
+// Dial dials a ReconnectingConn. The first connection is dialed according to the policy until it succeeds, the policy
+// stops or the context is done. The context does not affect redials, which stop when the connection is closed.
+func (d *Dialer) Dial(ctx context.Context) (*ReconnectingConn, error) {
+	redialCtx, cancel := context.WithCancel(context.Background())
+	c := &ReconnectingConn{
+		dialer: *d,
+		clock:  newRetryOptions(d.Options).clock,
+		ctx:    redialCtx,
+		cancel: cancel,
+		ready:  make(chan struct{}),
+		state:  ConnConnecting,
+	}
+	if c.dialer.BufferSize <= 0 {
+		c.dialer.BufferSize = defaultWriteBufferSize
+	}
+	c.notify(ConnConnecting, nil)
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		cancel()
+		c.notify(ConnClosed, err)
+		return nil, err
+	}
+	c.mu.Lock()
+	c.connected(conn)
+	c.unlock()
+	return c, nil
+}
+
+// ReconnectingConn is a net.Conn that redials the connection in the background when it is lost. While redialing, reads
+// block and writes are handled according to the WriteMode of the Dialer. It is safe for concurrent use.
+type ReconnectingConn struct {
+	dialer Dialer
+	clock  Clock
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// writeMu serializes writes with flushing the buffer after a redial.
+	writeMu sync.Mutex
+
+	mu            sync.Mutex
+	conn          net.Conn
+	ready         chan struct{}
+	state         ConnState
+	err           error
+	buf           []byte
+	localAddr     net.Addr
+	remoteAddr    net.Addr
+	readDeadline  time.Time
+	writeDeadline time.Time
+	changes       []connStateChange
+}
+
+var _ net.Conn = (*ReconnectingConn)(nil)
+
+type connStateChange struct {
+	state ConnState
+	err   error
+}
+
+// State returns the current state of the connection.
+func (c *ReconnectingConn) State() ConnState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// Read reads from the current connection, waiting until it is established. If the connection is lost, ErrDisconnected
+// is returned and the connection is redialed in the background.
+func (c *ReconnectingConn) Read(b []byte) (int, error) {
+	for {
+		c.mu.Lock()
+		conn, ready, err, deadline := c.conn, c.ready, c.err, c.readDeadline
+		c.mu.Unlock()
+		if err != nil {
+			return 0, err
+		}
+		if conn == nil {
+			if err := waitReady(c.clock, ready, deadline); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		n, err := conn.Read(b)
+		if err != nil && !isTimeout(err) {
+			if err := c.lost(conn, err); err != nil {
+				return n, err
+			}
+			if n > 0 {
+				return n, nil
+			}
+			return 0, ErrDisconnected
+		}
+		return n, err
+	}
+}
+
+// Write writes to the current connection. While disconnected, the data is buffered or ErrDisconnected is returned
+// according to the WriteMode of the Dialer. If the connection is lost during the write, it is redialed in the
+// background and the remaining data is handled the same way.
+func (c *ReconnectingConn) Write(b []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.mu.Lock()
+	conn, err := c.conn, c.err
+	if err == nil && conn == nil {
+		err = c.buffer(b)
+	}
+	c.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	if conn == nil {
+		return len(b), nil
+	}
+
+	n, err := conn.Write(b)
+	if err == nil || isTimeout(err) {
+		return n, err
+	}
+	if err := c.lost(conn, err); err != nil {
+		return n, err
+	}
+	c.mu.Lock()
+	err = c.buffer(b[n:])
+	c.mu.Unlock()
+	if err != nil {
+		return n, err
+	}
+	return len(b), nil
+}
+
+// Close closes the connection and stops redialing. Buffered writes are discarded.
+func (c *ReconnectingConn) Close() error {
+	c.cancel()
+	c.mu.Lock()
+	if c.state == ConnClosed {
+		c.unlock()
+		return nil
+	}
+	var err error
+	if c.conn != nil {
+		err = c.conn.Close()
+	}
+	c.closed(net.ErrClosed, nil)
+	c.unlock()
+	c.wg.Wait()
+	return err
+}
+
+// LocalAddr returns the local address of the current or last connection.
+func (c *ReconnectingConn) LocalAddr() net.Addr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.localAddr
+}
+
+// RemoteAddr returns the remote address of the current or last connection.
+func (c *ReconnectingConn) RemoteAddr() net.Addr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.remoteAddr
+}
+
+// SetDeadline sets the read and write deadlines of the current and all future connections.
+func (c *ReconnectingConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline, c.writeDeadline = t, t
+	if c.conn != nil {
+		return c.conn.SetDeadline(t)
+	}
+	return nil
+}
+
+// SetReadDeadline sets the read deadline of the current and all future connections. The deadline also applies to
+// waiting for a connection in Read.
+func (c *ReconnectingConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	if c.conn != nil {
+		return c.conn.SetReadDeadline(t)
+	}
+	return nil
+}
+
+// SetWriteDeadline sets the write deadline of the current and all future connections.
+func (c *ReconnectingConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeDeadline = t
+	if c.conn != nil {
+		return c.conn.SetWriteDeadline(t)
+	}
+	return nil
+}
+
+// dial dials a connection according to the policy.
+func (c *ReconnectingConn) dial(ctx context.Context) (net.Conn, error) {
+	dial := c.dialer.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	f := func(ctx context.Context) (net.Conn, error) {
+		return dial(ctx, c.dialer.Network, c.dialer.Address)
+	}
+	if c.dialer.Policy == nil {
+		return f(ctx)
+	}
+	return RetryWithDataContext(ctx, c.dialer.Policy, f, c.dialer.Options...)
+}
+
+// lost handles the loss of conn and starts redialing, unless it is already handled. It returns the error to return
+// instead of ErrDisconnected if the connection has been closed, which happens right away without a policy.
+func (c *ReconnectingConn) lost(conn net.Conn, err error) error {
+	c.mu.Lock()
+	defer c.unlock()
+	if c.err != nil {
+		return c.err
+	}
+	if c.conn != conn {
+		return nil
+	}
+	_ = conn.Close()
+	if c.dialer.Policy == nil {
+		c.closed(err, err)
+		return err
+	}
+	c.conn = nil
+	c.ready = make(chan struct{})
+	c.setState(ConnDisconnected, err)
+	c.setState(ConnConnecting, nil)
+
+	c.wg.Add(1)
+	go c.redial()
+	return nil
+}
+
+// redial dials a new connection and flushes the buffered writes to it.
+func (c *ReconnectingConn) redial() {
+	defer c.wg.Done()
+	for {
+		conn, err := c.dial(c.ctx)
+		if err != nil {
+			c.mu.Lock()
+			if c.state != ConnClosed {
+				c.closed(err, err)
+			}
+			c.unlock()
+			return
+		}
+
+		c.writeMu.Lock()
+		c.mu.Lock()
+		if c.state == ConnClosed {
+			c.unlock()
+			c.writeMu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		buf := c.buf
+		c.buf = nil
+		c.applyDeadlines(conn)
+		c.mu.Unlock()
+
+		_, err = conn.Write(buf)
+		c.mu.Lock()
+		switch {
+		case c.state == ConnClosed:
+			_ = conn.Close()
+			c.unlock()
+			c.writeMu.Unlock()
+			return
+		case err == nil:
+			c.connected(conn)
+			c.unlock()
+			c.writeMu.Unlock()
+			return
+		}
+		_ = conn.Close()
+		c.buf = buf
+		c.setState(ConnDisconnected, err)
+		c.setState(ConnConnecting, nil)
+		c.unlock()
+		c.writeMu.Unlock()
+	}
+}
+
+// connected makes conn the current connection. c.mu must be held.
+func (c *ReconnectingConn) connected(conn net.Conn) {
+	c.conn = conn
+	c.localAddr = conn.LocalAddr()
+	c.remoteAddr = conn.RemoteAddr()
+	c.applyDeadlines(conn)
+	close(c.ready)
+	c.setState(ConnConnected, nil)
+}
+
+// closed moves the connection to the closed state, after which all operations return err. c.mu must be held.
+func (c *ReconnectingConn) closed(err, cause error) {
+	c.conn = nil
+	c.err = err
+	c.buf = nil
+	select {
+	case <-c.ready:
+	default:
+		close(c.ready)
+	}
+	c.setState(ConnClosed, cause)
+}
+
+// buffer buffers b in BufferWrites mode or returns ErrDisconnected. c.mu must be held.
+func (c *ReconnectingConn) buffer(b []byte) error {
+	if c.dialer.WriteMode != BufferWrites {
+		return ErrDisconnected
+	}
+	if len(c.buf)+len(b) > c.dialer.BufferSize {
+		return ErrBufferFull
+	}
+	c.buf = append(c.buf, b...)
+	return nil
+}
+
+// applyDeadlines sets the stored deadlines on conn. c.mu must be held.
+func (c *ReconnectingConn) applyDeadlines(conn net.Conn) {
+	_ = conn.SetReadDeadline(c.readDeadline)
+	_ = conn.SetWriteDeadline(c.writeDeadline)
+}
+
+func (c *ReconnectingConn) setState(state ConnState, err error) {
+	c.state = state
+	c.changes = append(c.changes, connStateChange{state: state, err: err})
+}
+
+// unlock unlocks the mutex and afterwards notifies about the state changes made while holding it.
+func (c *ReconnectingConn) unlock() {
+	changes := c.changes
+	c.changes = nil
+	c.mu.Unlock()
+
+	for _, change := range changes {
+		c.notify(change.state, change.err)
+	}
+}
+
+func (c *ReconnectingConn) notify(state ConnState, err error) {
+	if c.dialer.OnStateChange != nil {
+		c.dialer.OnStateChange(state, err)
+	}
+}
+
+// waitReady waits until ready is closed or the deadline passes according to the clock.
+func waitReady(clock Clock, ready <-chan struct{}, deadline time.Time) error {
+	if deadline.IsZero() {
+		<-ready
+		return nil
+	}
+	timer := clock.NewTimer(deadline.Sub(clock.Now()))
+	defer timer.Stop()
+	select {
+	case <-ready:
+		return nil
+	case <-timer.C():
+		return os.ErrDeadlineExceeded
+	}
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,296 @@
+package backoff_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+	"github.com/ireward/wago/backoff/backofftest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testServer is a local TCP server greeting every connection with "hello\n" and collecting the received lines.
+type testServer struct {
+	addr     string
+	received chan string
+
+	mu    sync.Mutex
+	ln    net.Listener
+	conns []net.Conn
+}
+
+func newTestServer(t *testing.T) *testServer {
+	s := &testServer{received: make(chan string, 10)}
+	s.start(t, "127.0.0.1:0")
+	t.Cleanup(s.kill)
+	return s
+}
+
+func (s *testServer) start(t *testing.T, addr string) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		assert.FailNow(t, "listen failed", err)
+	}
+	s.mu.Lock()
+	s.ln = ln
+	s.addr = ln.Addr().String()
+	s.mu.Unlock()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go func() {
+				_, _ = conn.Write([]byte("hello\n"))
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					s.received <- scanner.Text()
+				}
+			}()
+		}
+	}()
+}
+
+func (s *testServer) restart(t *testing.T) {
+	s.start(t, s.addr)
+}
+
+// kill closes the listener and all connections.
+func (s *testServer) kill() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.ln.Close()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+// stateRecorder records the state changes of a ReconnectingConn.
+type stateRecorder struct {
+	mu     sync.Mutex
+	states []backoff.ConnState
+}
+
+func (r *stateRecorder) record(state backoff.ConnState, _ error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states = append(r.states, state)
+}
+
+func (r *stateRecorder) get() []backoff.ConnState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]backoff.ConnState(nil), r.states...)
+}
+
+func readHello(t *testing.T, conn net.Conn) {
+	buf := make([]byte, 6)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		assert.FailNow(t, "read failed", err)
+	}
+	assert.Equal(t, "hello\n", string(buf))
+}
+
+func TestDialerReconnect(t *testing.T) {
+	server := newTestServer(t)
+	var states stateRecorder
+	d := &backoff.Dialer{
+		Network:       "tcp",
+		Address:       server.addr,
+		Policy:        backoff.ConstantBackOff(10 * time.Millisecond),
+		OnStateChange: states.record,
+	}
+	conn, err := d.Dial(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	readHello(t, conn)
+
+	server.kill()
+	_, err = conn.Read(make([]byte, 1))
+	assert.Equal(t, backoff.ErrDisconnected, err)
+	_, err = conn.Write([]byte("lost\n"))
+	assert.Equal(t, backoff.ErrDisconnected, err)
+
+	server.restart(t)
+	readHello(t, conn)
+	assert.Equal(t, backoff.ConnConnected, conn.State())
+
+	_, err = conn.Write([]byte("sent\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "sent", <-server.received)
+
+	assert.NoError(t, conn.Close())
+	assert.Equal(t, []backoff.ConnState{
+		backoff.ConnConnecting,
+		backoff.ConnConnected,
+		backoff.ConnDisconnected,
+		backoff.ConnConnecting,
+		backoff.ConnConnected,
+		backoff.ConnClosed,
+	}, states.get())
+}
+
+func TestDialerBufferWrites(t *testing.T) {
+	server := newTestServer(t)
+	d := &backoff.Dialer{
+		Network:    "tcp",
+		Address:    server.addr,
+		Policy:     backoff.ConstantBackOff(10 * time.Millisecond),
+		WriteMode:  backoff.BufferWrites,
+		BufferSize: 16,
+	}
+	conn, err := d.Dial(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	readHello(t, conn)
+
+	server.kill()
+	_, err = conn.Read(make([]byte, 1))
+	assert.Equal(t, backoff.ErrDisconnected, err)
+
+	n, err := conn.Write([]byte("buffered\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 9, n)
+	_, err = conn.Write([]byte("too much data\n"))
+	assert.Equal(t, backoff.ErrBufferFull, err)
+
+	server.restart(t)
+	assert.Equal(t, "buffered", <-server.received)
+}
+
+func TestDialerGiveUp(t *testing.T) {
+	server := newTestServer(t)
+	var states stateRecorder
+	d := &backoff.Dialer{
+		Network:       "tcp",
+		Address:       server.addr,
+		Policy:        backoff.ConstantBackOff(time.Millisecond).With(backoff.MaxRetries(2)),
+		OnStateChange: states.record,
+	}
+	conn, err := d.Dial(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	readHello(t, conn)
+
+	server.kill()
+	_, err = conn.Read(make([]byte, 1))
+	assert.Equal(t, backoff.ErrDisconnected, err)
+
+	for conn.State() != backoff.ConnClosed {
+		time.Sleep(time.Millisecond)
+	}
+	_, err = conn.Read(make([]byte, 1))
+	assertRetryError(t, err, backoff.StopMaxRetries)
+	_, err = conn.Write([]byte("x"))
+	assertRetryError(t, err, backoff.StopMaxRetries)
+	assert.Equal(t, backoff.ConnClosed, states.get()[len(states.get())-1])
+}
+
+func TestDialerNoPolicy(t *testing.T) {
+	server := newTestServer(t)
+	var states stateRecorder
+	d := &backoff.Dialer{
+		Network:       "tcp",
+		Address:       server.addr,
+		OnStateChange: states.record,
+	}
+	conn, err := d.Dial(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	readHello(t, conn)
+
+	server.kill()
+	_, err = conn.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, backoff.ConnClosed, conn.State())
+	_, err = conn.Write([]byte("x"))
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, []backoff.ConnState{backoff.ConnConnecting, backoff.ConnConnected, backoff.ConnClosed}, states.get())
+}
+
+func TestDialerReadDeadlineClock(t *testing.T) {
+	server := newTestServer(t)
+	clock := backofftest.NewFakeClock(time.Now())
+	d := &backoff.Dialer{
+		Network: "tcp",
+		Address: server.addr,
+		Policy:  backoff.ConstantBackOff(time.Hour),
+		Options: []backoff.RetryOption{backoff.WithClock(clock)},
+	}
+	conn, err := d.Dial(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	readHello(t, conn)
+
+	server.kill()
+	_, err = conn.Read(make([]byte, 1))
+	assert.Equal(t, backoff.ErrDisconnected, err)
+
+	// The redial waits for an hour, and Read waits for the deadline according to the fake clock.
+	assert.NoError(t, conn.SetReadDeadline(clock.Now().Add(time.Second)))
+	done := make(chan error, 1)
+	go func() {
+		_, err := conn.Read(make([]byte, 1))
+		done <- err
+	}()
+	clock.BlockUntil(2)
+	clock.Advance(time.Second)
+	assert.ErrorIs(t, <-done, os.ErrDeadlineExceeded)
+}
+
+func TestDialerClose(t *testing.T) {
+	server := newTestServer(t)
+	d := &backoff.Dialer{
+		Network: "tcp",
+		Address: server.addr,
+		Policy:  backoff.ZeroBackOff(),
+	}
+	conn, err := d.Dial(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.NoError(t, conn.Close())
+	assert.NoError(t, conn.Close())
+	_, err = conn.Read(make([]byte, 1))
+	assert.True(t, errors.Is(err, net.ErrClosed))
+	_, err = conn.Write([]byte("x"))
+	assert.True(t, errors.Is(err, net.ErrClosed))
+}
+
+func TestDialerDialError(t *testing.T) {
+	server := newTestServer(t)
+	server.kill()
+
+	d := &backoff.Dialer{
+		Network: "tcp",
+		Address: server.addr,
+		Policy:  backoff.ZeroBackOff().With(backoff.MaxRetries(2)),
+	}
+	_, err := d.Dial(context.Background())
+	assert.Equal(t, 3, assertRetryError(t, err, backoff.StopMaxRetries).Attempts)
+}
@@ -0,0 +1,129 @@
+package backoff
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// maxAttemptErrors is the maximum number of attempt errors kept by a RetryError.
+const maxAttemptErrors = 100
+
+// StopReason describes why Retry stopped retrying an operation.
+type StopReason int
+
+const (
+	// StopPolicy indicates that the backoff policy returned Stop.
+	StopPolicy StopReason = iota
+	// StopMaxRetries indicates that the maximum number of retries configured with MaxRetries was reached.
+	StopMaxRetries
+	// StopTimeout indicates that the time configured with Timeout has passed.
+	StopTimeout
+	// StopCancel indicates that the context given to Cancel is done.
+	StopCancel
+	// StopBudget indicates that the RetryBudget given to Budget is exhausted.
+	StopBudget
+	// StopPermanent indicates that the operation returned a permanent error.
+	StopPermanent
+	// StopContext indicates that the context given to RetryContext is done.
+	StopContext
+)
+
+func (r StopReason) String() string {
+	switch r {
+	case StopPolicy:
+		return "policy stopped"
+	case StopMaxRetries:
+		return "max retries reached"
+	case StopTimeout:
+		return "timeout"
+	case StopCancel:
+		return "cancelled"
+	case StopBudget:
+		return "retry budget exhausted"
+	case StopPermanent:
+		return "permanent error"
+	case StopContext:
+		return "context done"
+	}
+	return "unknown"
+}
+
+// RetryError is returned by Retry if the operation did not succeed. It matches the errors of all attempts with
+// errors.Is and errors.As, and unwraps to the error of the last attempt.
+type RetryError struct {
+	// Err is the error of the last attempt. If Reason is StopPermanent, it is the error wrapped with Permanent.
+	// If Reason is StopContext, it additionally wraps the context error.
+	Err error
+
+	// Errors are the errors of all attempts in order. Only the most recent 100 errors are kept.
+	Errors []error
+
+	// Attempts is the number of attempts made.
+	Attempts int
+
+	// Elapsed is the time passed between the start of the first attempt and giving up.
+	Elapsed time.Duration
+
+	// Reason describes why no more attempts were made.
+	Reason StopReason
+}
+
+func (e *RetryError) Error() string {
+	return "backoff: giving up after " + strconv.Itoa(e.Attempts) + " attempts in " + e.Elapsed.String() +
+		" (" + e.Reason.String() + "): " + e.Err.Error()
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+func (e *RetryError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *RetryError) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// newRetryError creates a RetryError for the given attempt errors.
+func newRetryError(reason StopReason, err error, errs []error, attempts int, elapsed time.Duration) *RetryError {
+	return &RetryError{
+		Err:      err,
+		Errors:   errs,
+		Attempts: attempts,
+		Elapsed:  elapsed,
+		Reason:   reason,
+	}
+}
+
+// appendAttemptError appends err to errs and drops the oldest error if more than maxAttemptErrors are kept.
+func appendAttemptError(errs []error, err error) []error {
+	if len(errs) == maxAttemptErrors {
+		errs = errs[1:]
+	}
+	return append(errs, err)
+}
+
+// stopReasoner is implemented by options that can stop a backoff policy, to report why the policy stopped.
+type stopReasoner interface {
+	stopReason() StopReason
+}
+
+// stopReason returns why the backoff policy p stopped.
+func stopReason(p Policy) StopReason {
+	if s, ok := p.(stopReasoner); ok {
+		return s.stopReason()
+	}
+	return StopPolicy
+}
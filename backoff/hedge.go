@@ -0,0 +1,112 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Hedge calls the function f and starts another concurrent attempt every time the duration returned by the backoff
+// policy passes without a successful result. The first successful result is returned and the context passed to the
+// remaining attempts is cancelled. Failed attempts do not start the next attempt earlier, unless it was held back by
+// MaxConcurrentAttempts. If the functions returns a permanent error, no more attempts are started.
+// If all attempts fail, a *RetryError containing the errors of all attempts is returned.
+// Results rejected by RetryOnResult count as failed attempts. Since attempts overlap, OnRetry is not called and delays
+// requested with RetryAfter are ignored.
+func Hedge[T any](ctx context.Context, p Policy, f func(ctx context.Context) (T, error),
+	opts ...RetryOption) (v T, err error) {
+	o := newRetryOptions(opts)
+	if err := checkResultType[T](o); err != nil {
+		return v, err
+	}
+	p = p.New()
+	setClock(p, o.clock)
+
+	hedgeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan attemptResult[T])
+
+	start := o.clock.Now()
+	var (
+		attempts int
+		running  int
+		pending  int
+		errs     []error
+		timer    Timer
+		timerC   <-chan time.Time
+	)
+	giveUp := func(reason StopReason, err error) error {
+		return newRetryError(reason, err, errs, attempts, o.clock.Now().Sub(start))
+	}
+	launch := func() {
+		attempts++
+		running++
+		go func() {
+			v, err := runAttempt(hedgeCtx, o, f)
+			select {
+			case results <- attemptResult[T]{v: v, err: err}:
+			case <-hedgeCtx.Done():
+			}
+		}()
+	}
+	schedule := func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		timer, timerC = nil, nil
+		if duration := p.NextBackOff(); duration != Stop {
+			timer = o.clock.NewTimer(duration)
+			timerC = timer.C()
+		}
+	}
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	launch()
+	schedule()
+	for {
+		select {
+		case r := <-results:
+			running--
+			if r.err == nil && o.retryResult != nil && o.retryResult(r.v) {
+				r.err = ErrUnacceptableResult
+			}
+			feedback(p, r.err)
+			if r.err == nil {
+				return r.v, nil
+			}
+			v = r.v
+			errs = appendAttemptError(errs, r.err)
+
+			var permanent *permanentError
+			if errors.As(r.err, &permanent) {
+				return v, giveUp(StopPermanent, permanent.Unwrap())
+			}
+
+			switch {
+			case pending > 0:
+				pending--
+				launch()
+			case running == 0 && timerC == nil:
+				return v, giveUp(stopReason(p), r.err)
+			}
+		case <-timerC:
+			if o.maxConcurrency > 0 && running >= o.maxConcurrency {
+				pending++
+			} else {
+				launch()
+			}
+			schedule()
+		case <-ctx.Done():
+			err := ctx.Err()
+			if len(errs) > 0 {
+				err = &contextError{ctxErr: err, err: errs[len(errs)-1]}
+			}
+			return v, giveUp(StopContext, err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package backoff_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+	"github.com/ireward/wago/backoff/backofftest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHedge(t *testing.T) {
+	clock := backofftest.NewFakeClock(start)
+
+	var count int32
+	cancelled := make(chan struct{})
+	go func() {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}()
+	v, err := backoff.Hedge(context.Background(), backoff.ConstantBackOff(time.Second),
+		func(ctx context.Context) (int32, error) {
+			attempt := atomic.AddInt32(&count, 1)
+			if attempt == 1 {
+				<-ctx.Done()
+				close(cancelled)
+				return 0, ctx.Err()
+			}
+			return attempt, nil
+		}, backoff.WithClock(clock))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, v)
+	<-cancelled
+}
+
+func TestHedgeAllFailed(t *testing.T) {
+	clock := backofftest.NewFakeClock(start)
+	p := backoff.ConstantBackOff(time.Second).With(backoff.MaxRetries(2))
+
+	// Failed attempts do not start the next one before the delay passes.
+	go func() {
+		for i := 0; i < 2; i++ {
+			clock.BlockUntil(1)
+			clock.Advance(time.Second)
+		}
+	}()
+	var count int32
+	_, err := backoff.Hedge(context.Background(), p, func(context.Context) (int, error) {
+		atomic.AddInt32(&count, 1)
+		return 0, errTest
+	}, backoff.WithClock(clock))
+	retryErr := assertRetryError(t, err, backoff.StopMaxRetries)
+	assert.Equal(t, 3, retryErr.Attempts)
+	assert.Equal(t, 2*time.Second, retryErr.Elapsed)
+	assert.Equal(t, []error{errTest, errTest, errTest}, retryErr.Errors)
+	assert.EqualValues(t, 3, count)
+}
+
+func TestHedgeRetryOnResult(t *testing.T) {
+	var count int32
+	v, err := backoff.Hedge(context.Background(), backoff.ZeroBackOff(), func(context.Context) (int32, error) {
+		return atomic.AddInt32(&count, 1), nil
+	}, backoff.RetryOnResult(func(v int32) bool {
+		return v < 3
+	}))
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, v, int32(3))
+}
+
+func TestHedgeMaxConcurrentAttempts(t *testing.T) {
+	clock := backofftest.NewFakeClock(start)
+	p := backoff.ConstantBackOff(time.Second).With(backoff.MaxRetries(1))
+
+	var count int32
+	started, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		<-started
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+		time.Sleep(10 * time.Millisecond)
+		assert.EqualValues(t, 1, atomic.LoadInt32(&count))
+		close(release)
+	}()
+	v, err := backoff.Hedge(context.Background(), p, func(context.Context) (int32, error) {
+		attempt := atomic.AddInt32(&count, 1)
+		if attempt == 1 {
+			close(started)
+			<-release
+			return 0, errTest
+		}
+		return attempt, nil
+	}, backoff.WithClock(clock), backoff.MaxConcurrentAttempts(1))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, v)
+}
+
+func TestHedgePermanentError(t *testing.T) {
+	_, err := backoff.Hedge(context.Background(), backoff.ZeroBackOff(), func(context.Context) (int, error) {
+		return 0, backoff.Permanent(errTest)
+	})
+	assert.Equal(t, errTest, assertRetryError(t, err, backoff.StopPermanent).Err)
+}
+
+func TestHedgeCancel(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	clock := backofftest.NewFakeClock(start)
+
+	go func() {
+		clock.BlockUntil(1)
+		ctxCancel()
+	}()
+	_, err := backoff.Hedge(ctx, backoff.ConstantBackOff(time.Hour), func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, errors.New("abandoned")
+	}, backoff.WithClock(clock))
+	assert.ErrorIs(t, err, context.Canceled)
+	assertRetryError(t, err, backoff.StopContext)
+}
@@ -0,0 +1,131 @@
+package backoff
+
+import (
+	"math"
+	"time"
+)
+
+// FullJitterBackOff returns an exponential backoff policy with "full jitter" as described in the AWS architecture
+// blog. The n-th delay is a random value in the interval [0, min(max, base * 2^n)).
+// Random values are taken from r, which may be nil to use DefaultRandom(). A Random created with NewRandom makes the
+// sequence of delays reproducible.
+func FullJitterBackOff(base, max time.Duration, r Random) *BackOff {
+	return NewBackOff(&fullJitterPolicy{
+		base:   base,
+		max:    max,
+		random: orDefault(r),
+	})
+}
+
+// EqualJitterBackOff returns an exponential backoff policy with "equal jitter" as described in the AWS architecture
+// blog. The n-th delay is a random value in the interval [d/2, d) with d = min(max, base * 2^n).
+// Random values are taken from r, which may be nil to use DefaultRandom(). A Random created with NewRandom makes the
+// sequence of delays reproducible.
+func EqualJitterBackOff(base, max time.Duration, r Random) *BackOff {
+	return NewBackOff(&equalJitterPolicy{
+		base:   base,
+		max:    max,
+		random: orDefault(r),
+	})
+}
+
+// DecorrelatedJitterBackOff returns a backoff policy with "decorrelated jitter" as described in the AWS architecture
+// blog. Each delay is a random value in the interval [base, 3 * previous delay) capped at max, starting with base.
+// Random values are taken from r, which may be nil to use DefaultRandom(). A Random created with NewRandom makes the
+// sequence of delays reproducible.
+func DecorrelatedJitterBackOff(base, max time.Duration, r Random) *BackOff {
+	return NewBackOff(&decorrelatedJitterPolicy{
+		base:   base,
+		max:    max,
+		random: orDefault(r),
+		sleep:  base,
+	})
+}
+
+type fullJitterPolicy struct {
+	base    time.Duration
+	max     time.Duration
+	random  Random
+	attempt int
+}
+
+func (b *fullJitterPolicy) NextBackOff() time.Duration {
+	d := exponentialCap(b.base, b.max, &b.attempt)
+	return time.Duration(b.random.Float64() * float64(d))
+}
+
+func (b *fullJitterPolicy) New() Policy {
+	return &fullJitterPolicy{
+		base:   b.base,
+		max:    b.max,
+		random: b.random,
+	}
+}
+
+func (b *fullJitterPolicy) spec() (string, bool) {
+	return "full_jitter(base=" + b.base.String() + ",max=" + b.max.String() + ")", true
+}
+
+type equalJitterPolicy struct {
+	base    time.Duration
+	max     time.Duration
+	random  Random
+	attempt int
+}
+
+func (b *equalJitterPolicy) NextBackOff() time.Duration {
+	d := exponentialCap(b.base, b.max, &b.attempt) / 2
+	return d + time.Duration(b.random.Float64()*float64(d))
+}
+
+func (b *equalJitterPolicy) New() Policy {
+	return &equalJitterPolicy{
+		base:   b.base,
+		max:    b.max,
+		random: b.random,
+	}
+}
+
+func (b *equalJitterPolicy) spec() (string, bool) {
+	return "equal_jitter(base=" + b.base.String() + ",max=" + b.max.String() + ")", true
+}
+
+type decorrelatedJitterPolicy struct {
+	base   time.Duration
+	max    time.Duration
+	random Random
+	sleep  time.Duration
+}
+
+func (b *decorrelatedJitterPolicy) NextBackOff() time.Duration {
+	upper := 3 * float64(b.sleep)
+	d := float64(b.base) + b.random.Float64()*(upper-float64(b.base))
+	if d > float64(b.max) {
+		d = float64(b.max)
+	}
+	b.sleep = time.Duration(d)
+	return b.sleep
+}
+
+func (b *decorrelatedJitterPolicy) New() Policy {
+	return &decorrelatedJitterPolicy{
+		base:   b.base,
+		max:    b.max,
+		random: b.random,
+		sleep:  b.base,
+	}
+}
+
+func (b *decorrelatedJitterPolicy) spec() (string, bool) {
+	return "decorrelated_jitter(base=" + b.base.String() + ",max=" + b.max.String() + ")", true
+}
+
+// exponentialCap returns min(max, base * 2^attempt) and increments attempt as long as the cap is not reached.
+func exponentialCap(base, max time.Duration, attempt *int) time.Duration {
+	d := float64(base) * math.Pow(2, float64(*attempt))
+	if d >= float64(max) {
+		return max
+	}
+	*attempt++
+	return time.Duration(d)
+}
@@ -0,0 +1,81 @@
+package backoff_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	jitterBase = 10 * time.Millisecond
+	jitterMax  = time.Second
+	jitterRuns = 20
+)
+
+func nextBackOffs(p backoff.Policy, n int) []time.Duration {
+	p = p.New()
+	delays := make([]time.Duration, n)
+	for i := range delays {
+		delays[i] = p.NextBackOff()
+	}
+	return delays
+}
+
+func TestFullJitterBackOff(t *testing.T) {
+	p := backoff.FullJitterBackOff(jitterBase, jitterMax, backoff.NewRandom(1))
+
+	for i, d := range nextBackOffs(p, jitterRuns) {
+		upper := jitterBase << i
+		if upper > jitterMax {
+			upper = jitterMax
+		}
+		assert.GreaterOrEqual(t, d, time.Duration(0))
+		assert.Less(t, d, upper)
+	}
+}
+
+func TestEqualJitterBackOff(t *testing.T) {
+	p := backoff.EqualJitterBackOff(jitterBase, jitterMax, backoff.NewRandom(1))
+
+	for i, d := range nextBackOffs(p, jitterRuns) {
+		upper := jitterBase << i
+		if upper > jitterMax {
+			upper = jitterMax
+		}
+		assert.GreaterOrEqual(t, d, upper/2)
+		assert.Less(t, d, upper)
+	}
+}
+
+func TestDecorrelatedJitterBackOff(t *testing.T) {
+	p := backoff.DecorrelatedJitterBackOff(jitterBase, jitterMax, backoff.NewRandom(1))
+
+	prev := jitterBase
+	for _, d := range nextBackOffs(p, jitterRuns) {
+		assert.GreaterOrEqual(t, d, jitterBase)
+		assert.LessOrEqual(t, d, jitterMax)
+		assert.LessOrEqual(t, d, 3*prev)
+		prev = d
+	}
+}
+
+func TestJitterBackOffReproducible(t *testing.T) {
+	constructors := map[string]func(base, max time.Duration, r backoff.Random) *backoff.BackOff{
+		"full":         backoff.FullJitterBackOff,
+		"equal":        backoff.EqualJitterBackOff,
+		"decorrelated": backoff.DecorrelatedJitterBackOff,
+	}
+	for name, constructor := range constructors {
+		t.Run(name, func(t *testing.T) {
+			p1 := constructor(jitterBase, jitterMax, backoff.NewRandom(42))
+			p2 := constructor(jitterBase, jitterMax, backoff.NewRandom(42))
+			assert.Equal(t, nextBackOffs(p1, jitterRuns), nextBackOffs(p2, jitterRuns))
+
+			p3 := constructor(jitterBase, jitterMax, nil)
+			assert.Len(t, nextBackOffs(p3, jitterRuns), jitterRuns)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package backoff
+
+import (
+	"context"
+	"time"
+
+	"github.com/ireward/wago/logger"
+	"github.com/ireward/wago/logger/tag"
+)
+
+// Notify is called after a failed attempt with the number of the attempt starting at 1, the error returned by
+// the attempt and the duration to wait before the next attempt.
+type Notify func(attempt int, err error, next time.Duration)
+
+// LogNotify returns a Notify that logs every retried attempt with the logger attached to ctx.
+func LogNotify(ctx context.Context) Notify {
+	l := logger.FromCtx(ctx)
+	return func(attempt int, err error, next time.Duration) {
+		l.Warn("retrying failed operation",
+			tag.NewIntTag("attempt", attempt),
+			tag.NewDurationTag("delay", next),
+			tag.NewErrorTag(err),
+		)
+	}
+}
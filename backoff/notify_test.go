@@ -0,0 +1,81 @@
+package backoff_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+	"github.com/ireward/wago/logger"
+	"github.com/ireward/wago/logger/tag"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingLogger struct {
+	logger.Logger
+	msgs []string
+	tags [][]tag.Tag
+}
+
+func (l *recordingLogger) Warn(msg string, tags ...tag.Tag) {
+	l.msgs = append(l.msgs, msg)
+	l.tags = append(l.tags, tags)
+}
+
+func TestOnRetry(t *testing.T) {
+	const interval = time.Millisecond
+
+	var (
+		count    int
+		attempts []int
+	)
+	err := backoff.Retry(backoff.ConstantBackOff(interval), func() error {
+		if count >= retryCount {
+			return nil
+		}
+		count++
+		return errTest
+	}, backoff.OnRetry(func(attempt int, err error, next time.Duration) {
+		assert.ErrorIs(t, err, errTest)
+		assert.Equal(t, interval, next)
+		attempts = append(attempts, attempt)
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, attempts)
+}
+
+func TestOnRetryNotCalledOnStop(t *testing.T) {
+	var attempts []int
+	err := backoff.Retry(backoff.ZeroBackOff().With(backoff.MaxRetries(2)), func() error {
+		return errTest
+	}, backoff.OnRetry(func(attempt int, _ error, _ time.Duration) {
+		attempts = append(attempts, attempt)
+	}))
+	assert.ErrorIs(t, err, errTest)
+	assert.Equal(t, []int{1, 2}, attempts)
+}
+
+func TestLogNotify(t *testing.T) {
+	l := &recordingLogger{}
+	ctx := logger.WithCtx(context.Background(), l)
+
+	var count uint
+	err := backoff.RetryContext(ctx, backoff.ZeroBackOff(), func(context.Context) error {
+		if count >= 2 {
+			return nil
+		}
+		count++
+		return errTest
+	}, backoff.OnRetry(backoff.LogNotify(ctx)))
+	assert.NoError(t, err)
+	assert.Len(t, l.msgs, 2)
+	for i, tags := range l.tags {
+		assert.Len(t, tags, 3)
+		assert.Equal(t, "attempt", tags[0].Key())
+		assert.EqualValues(t, i+1, tags[0].Value())
+		assert.Equal(t, "delay", tags[1].Key())
+		assert.Equal(t, "error", tags[2].Key())
+		assert.Equal(t, errTest.Error(), tags[2].Value())
+	}
+}
@@ -2,7 +2,7 @@ package backoff
 
 import (
 	"context"
-	"math/rand"
+	"strconv"
 	"time"
 )
 
@@ -18,13 +18,30 @@ func (f optionFunc) apply(p Policy) Policy {
 	return f(p)
 }
 
-// statelessOptionFunc wraps a function modifying the duration so it satisfies the Option interface.
-type statelessOptionFunc func(time.Duration) time.Duration
+// limiter is implemented by policies and options that limit the durations returned by NextBackOff().
+// It allows Retry to apply the same limits to delays that are not returned by the policy.
+type limiter interface {
+	limit(d time.Duration) time.Duration
+}
 
-func (f statelessOptionFunc) apply(p Policy) Policy {
-	return &statelessOption{
-		delegate: p,
-		f:        f,
+// limit applies the limits of p to the duration d.
+func limit(p Policy, d time.Duration) time.Duration {
+	if l, ok := p.(limiter); ok {
+		return l.limit(d)
+	}
+	return d
+}
+
+// feedbackPolicy is implemented by policies and options that learn from the outcome of the retried operation.
+// Retry reports the outcome of every attempt to the policy instance created with New().
+type feedbackPolicy interface {
+	feedback(err error)
+}
+
+// feedback reports the outcome of an attempt to p if it is a feedbackPolicy. A nil error indicates success.
+func feedback(p Policy, err error) {
+	if fp, ok := p.(feedbackPolicy); ok {
+		fp.feedback(err)
 	}
 }
 
@@ -41,93 +58,171 @@ func MaxRetries(max int) Option {
 
 // MaxInterval configures a backoff policy to not return longer intervals when NextBackOff() is called.
 func MaxInterval(maxInterval time.Duration) Option {
-	return statelessOptionFunc(func(duration time.Duration) time.Duration {
-		if duration > maxInterval {
-			return maxInterval
-		}
-		return duration
-	})
+	return &statelessOption{
+		f: func(duration time.Duration) time.Duration {
+			if duration > maxInterval {
+				return maxInterval
+			}
+			return duration
+		},
+		limiting: true,
+		name:     "max_interval(" + maxInterval.String() + ")",
+	}
 }
 
 // Timeout configures a backoff policy to stop when the current time passes the time given with timeout.
+// The current time is taken from the Clock given to Retry.
 func Timeout(timeout time.Time) Option {
-	return statelessOptionFunc(func(duration time.Duration) time.Duration {
-		if duration == Stop {
-			return Stop
-		}
-		now := time.Now()
-		if now.After(timeout) {
-			return Stop
+	return optionFunc(func(p Policy) Policy {
+		return &timeoutOption{
+			delegate: p,
+			timeout:  timeout,
+			clock:    SystemClock(),
 		}
-		if now.Add(duration).After(timeout) {
-			return timeout.Sub(now)
+	})
+}
+
+// MaxElapsedTime configures a backoff policy to stop when the given duration has passed since the policy instance
+// was created with New(), i.e. since the start of Retry. Unlike Timeout, the option can be used in policies shared
+// by multiple invocations of Retry.
+func MaxElapsedTime(d time.Duration) Option {
+	return optionFunc(func(p Policy) Policy {
+		return &maxElapsedTimeOption{
+			timeoutOption: timeoutOption{
+				delegate: p,
+				clock:    SystemClock(),
+			},
+			maxElapsedTime: d,
 		}
-		return duration
 	})
 }
 
 // Cancel configures a backoff policy to stop if the given context is done.
 func Cancel(ctx context.Context) Option {
-	return statelessOptionFunc(func(duration time.Duration) time.Duration {
-		select {
-		case <-ctx.Done():
-			return Stop
-		default:
-		}
-		return duration
-	})
+	return &statelessOption{
+		f: func(duration time.Duration) time.Duration {
+			select {
+			case <-ctx.Done():
+				return Stop
+			default:
+			}
+			return duration
+		},
+		reason: StopCancel,
+	}
 }
 
 // Jitter configures a backoff policy to randomly modify the duration by the given factor.
 // The modified duration is a random value in the interval [randomFactor * duration, duration).
+// Random values are taken from DefaultRandom().
 func Jitter(randomFactor float64) Option {
-	return statelessOptionFunc(func(duration time.Duration) time.Duration {
-		if duration == Stop {
-			return Stop
-		}
-		if randomFactor <= 0 {
-			return duration
-		}
-		delta := randomFactor * float64(duration)
-		return time.Duration(float64(duration) - rand.Float64()*delta)
-	})
+	return JitterRandom(randomFactor, nil)
+}
+
+// JitterRandom is like Jitter, but takes the random values from r, which may be nil to use DefaultRandom().
+func JitterRandom(randomFactor float64, r Random) Option {
+	r = orDefault(r)
+	return &statelessOption{
+		f: func(duration time.Duration) time.Duration {
+			if duration == Stop {
+				return Stop
+			}
+			if randomFactor <= 0 {
+				return duration
+			}
+			delta := randomFactor * float64(duration)
+			return time.Duration(float64(duration) - r.Float64()*delta)
+		},
+		name: "jitter(" + formatFloat(randomFactor) + ")",
+	}
 }
 
+// statelessOption applies a function to the durations of the delegate. If limiting is set, the function also
+// limits delays given with RetryAfter. The name describes the option in the syntax of Parse.
 type statelessOption struct {
 	delegate Policy
 	f        func(time.Duration) time.Duration
+	limiting bool
+	reason   StopReason
+	stopped  bool
+	name     string
 }
 
 func (o *statelessOption) apply(p Policy) Policy {
 	return &statelessOption{
 		delegate: p,
 		f:        o.f,
+		limiting: o.limiting,
+		reason:   o.reason,
+		name:     o.name,
 	}
 }
 
+func (o *statelessOption) setClock(c Clock) {
+	setClock(o.delegate, c)
+}
+
 func (o *statelessOption) NextBackOff() time.Duration {
-	return o.f(o.delegate.NextBackOff())
+	return o.call(o.delegate.NextBackOff())
 }
 
 func (o *statelessOption) New() Policy {
 	return &statelessOption{
 		delegate: o.delegate.New(),
 		f:        o.f,
+		limiting: o.limiting,
+		reason:   o.reason,
+		name:     o.name,
+	}
+}
+
+// call applies the function to the duration returned by the delegate and records whether it stopped the policy.
+func (o *statelessOption) call(d time.Duration) time.Duration {
+	r := o.f(d)
+	if r == Stop && d != Stop {
+		o.stopped = true
+	}
+	return r
+}
+
+func (o *statelessOption) spec() (string, bool) {
+	return optionSpec(o.delegate, o.name)
+}
+
+func (o *statelessOption) stopReason() StopReason {
+	if o.stopped {
+		return o.reason
 	}
+	return stopReason(o.delegate)
+}
+
+func (o *statelessOption) feedback(err error) {
+	feedback(o.delegate, err)
+}
+
+func (o *statelessOption) limit(d time.Duration) time.Duration {
+	d = limit(o.delegate, d)
+	if o.limiting {
+		return o.call(d)
+	}
+	return d
 }
 
 type maxRetriesOption struct {
 	delegate Policy
 	maxTries int
 	numTries int
+	stopped  bool
 }
 
 func (b *maxRetriesOption) NextBackOff() time.Duration {
 	if b.maxTries == 0 {
+		b.stopped = true
 		return Stop
 	}
 	if b.maxTries > 0 {
 		if b.maxTries <= b.numTries {
+			b.stopped = true
 			return Stop
 		}
 		b.numTries++
@@ -142,3 +237,110 @@ func (b *maxRetriesOption) New() Policy {
 		numTries: 0,
 	}
 }
+
+func (b *maxRetriesOption) setClock(c Clock) {
+	setClock(b.delegate, c)
+}
+
+func (b *maxRetriesOption) limit(d time.Duration) time.Duration {
+	return limit(b.delegate, d)
+}
+
+func (b *maxRetriesOption) feedback(err error) {
+	feedback(b.delegate, err)
+}
+
+func (b *maxRetriesOption) spec() (string, bool) {
+	return optionSpec(b.delegate, "max_retries("+strconv.Itoa(b.maxTries)+")")
+}
+
+func (b *maxRetriesOption) stopReason() StopReason {
+	if b.stopped {
+		return StopMaxRetries
+	}
+	return stopReason(b.delegate)
+}
+
+type timeoutOption struct {
+	delegate Policy
+	timeout  time.Time
+	clock    Clock
+	stopped  bool
+}
+
+func (b *timeoutOption) NextBackOff() time.Duration {
+	return b.clamp(b.delegate.NextBackOff())
+}
+
+func (b *timeoutOption) limit(d time.Duration) time.Duration {
+	return b.clamp(limit(b.delegate, d))
+}
+
+func (b *timeoutOption) clamp(duration time.Duration) time.Duration {
+	if duration == Stop {
+		return Stop
+	}
+	now := b.clock.Now()
+	if !now.Before(b.timeout) {
+		b.stopped = true
+		return Stop
+	}
+	if now.Add(duration).After(b.timeout) {
+		return b.timeout.Sub(now)
+	}
+	return duration
+}
+
+func (b *timeoutOption) New() Policy {
+	return &timeoutOption{
+		delegate: b.delegate.New(),
+		timeout:  b.timeout,
+		clock:    b.clock,
+	}
+}
+
+func (b *timeoutOption) setClock(c Clock) {
+	b.clock = c
+	setClock(b.delegate, c)
+}
+
+func (b *timeoutOption) feedback(err error) {
+	feedback(b.delegate, err)
+}
+
+func (b *timeoutOption) spec() (string, bool) {
+	return optionSpec(b.delegate, "timeout("+b.timeout.Format(time.RFC3339Nano)+")")
+}
+
+func (b *timeoutOption) stopReason() StopReason {
+	if b.stopped {
+		return StopTimeout
+	}
+	return stopReason(b.delegate)
+}
+
+type maxElapsedTimeOption struct {
+	timeoutOption
+	maxElapsedTime time.Duration
+}
+
+func (b *maxElapsedTimeOption) New() Policy {
+	return &maxElapsedTimeOption{
+		timeoutOption: timeoutOption{
+			delegate: b.delegate.New(),
+			timeout:  b.clock.Now().Add(b.maxElapsedTime),
+			clock:    b.clock,
+		},
+		maxElapsedTime: b.maxElapsedTime,
+	}
+}
+
+// setClock sets the clock and restarts the elapsed time with it.
+func (b *maxElapsedTimeOption) setClock(c Clock) {
+	b.timeoutOption.setClock(c)
+	b.timeout = c.Now().Add(b.maxElapsedTime)
+}
+
+func (b *maxElapsedTimeOption) spec() (string, bool) {
+	return optionSpec(b.delegate, "max_elapsed_time("+b.maxElapsedTime.String()+")")
+}
@@ -1,4 +1,4 @@
-package backoff
+package backoff_test
 
 import (
 	"context"
@@ -7,16 +7,19 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ireward/wago/backoff"
+	"github.com/ireward/wago/backoff/backofftest"
+
 	"github.com/stretchr/testify/assert"
 )
 
 func TestMaxRetries(t *testing.T) {
 	const retries = 10
 
-	p := ZeroBackOff().With(MaxRetries(retries))
+	p := backoff.ZeroBackOff().With(backoff.MaxRetries(retries))
 
 	var count uint
-	err := Retry(p, func() error {
+	err := backoff.Retry(p, func() error {
 		count++
 		return errTest
 	})
@@ -27,11 +30,11 @@ func TestMaxRetries(t *testing.T) {
 func TestMaxRetriesNew(t *testing.T) {
 	const retries = 10
 
-	p := ZeroBackOff().With(MaxRetries(retries))
+	p := backoff.ZeroBackOff().With(backoff.MaxRetries(retries))
 
 	for i := 0; i < 3; i++ {
 		var count uint
-		err := Retry(p, func() error {
+		err := backoff.Retry(p, func() error {
 			count++
 			return errTest
 		})
@@ -47,12 +50,12 @@ func TestMaxRetriesParallel(t *testing.T) {
 	)
 
 	var wg sync.WaitGroup
-	p := ZeroBackOff().With(MaxRetries(retries))
+	p := backoff.ZeroBackOff().With(backoff.MaxRetries(retries))
 
 	test := func() {
 		defer wg.Done()
 		var count uint
-		err := Retry(p, func() error {
+		err := backoff.Retry(p, func() error {
 			count++
 			return errTest
 		})
@@ -70,16 +73,17 @@ func TestMaxRetriesParallel(t *testing.T) {
 func TestMaxInterval(t *testing.T) {
 	const interval = 20 * time.Millisecond
 
-	p := ConstantBackOff(10 * interval).With(MaxInterval(interval))
+	p := backoff.ConstantBackOff(10 * interval).With(backoff.MaxInterval(interval))
+	clock := backofftest.NewRecorder(start)
 
 	var (
 		count uint
 		last  time.Time
 	)
-	err := Retry(p, func() error {
-		now := time.Now()
+	err := backoff.Retry(p, func() error {
+		now := clock.Now()
 		if count > 0 {
-			assertInterval(t, interval, now.Sub(last))
+			assert.Equal(t, interval, now.Sub(last))
 		}
 		last = now
 		if count >= 10 {
@@ -87,33 +91,36 @@ func TestMaxInterval(t *testing.T) {
 		}
 		count++
 		return errTest
-	})
+	}, backoff.WithClock(clock))
 	assert.NoError(t, err)
 	assert.EqualValues(t, 10, count)
 }
 
 func TestTimeout(t *testing.T) {
-	timeout := time.Now().Add(20 * time.Millisecond)
-	max := timeout.Add(intervalDelta)
+	const interval = 300 * time.Millisecond
+
+	clock := backofftest.NewRecorder(start)
+	timeout := start.Add(time.Second)
 
-	p := ZeroBackOff().With(Timeout(timeout))
+	p := backoff.ConstantBackOff(interval).With(backoff.Timeout(timeout))
 
-	err := Retry(p, func() error {
-		assert.LessOrEqual(t, time.Now().UnixNano(), max.UnixNano())
+	err := backoff.Retry(p, func() error {
+		assert.False(t, clock.Now().After(timeout))
 		return errTest
-	})
+	}, backoff.WithClock(clock))
 	assert.True(t, errors.Is(err, errTest))
-	assert.GreaterOrEqual(t, time.Now().UnixNano(), timeout.UnixNano())
+	assert.Equal(t, timeout, clock.Now())
+	assert.Equal(t, []time.Duration{interval, interval, interval, 100 * time.Millisecond}, clock.Delays())
 }
 
 func TestCancel(t *testing.T) {
 	ctx, ctxCancel := context.WithCancel(context.Background())
 
-	p := ZeroBackOff().With(Cancel(ctx))
+	p := backoff.ZeroBackOff().With(backoff.Cancel(ctx))
 
 	stopped := make(chan struct{}, 1)
 	go func() {
-		err := Retry(p, func() error {
+		err := backoff.Retry(p, func() error {
 			return errTest
 		})
 		assert.True(t, errors.Is(err, errTest))
@@ -133,21 +140,22 @@ func TestJitter(t *testing.T) {
 	const (
 		interval    = 20 * time.Millisecond
 		factor      = 0.5
-		minInterval = time.Duration(float64(interval)*factor) - intervalDelta
-		maxInterval = interval + intervalDelta
+		minInterval = time.Duration(float64(interval) * factor)
+		maxInterval = interval
 	)
 
-	p := ConstantBackOff(interval).With(Jitter(factor))
+	p := backoff.ConstantBackOff(interval).With(backoff.Jitter(factor))
+	clock := backofftest.NewRecorder(start)
 
 	var (
 		count uint
 		last  time.Time
 	)
-	err := Retry(p, func() error {
-		now := time.Now()
+	err := backoff.Retry(p, func() error {
+		now := clock.Now()
 		if count > 0 {
-			assert.GreaterOrEqual(t, now.Sub(last).Microseconds(), minInterval.Microseconds())
-			assert.Less(t, now.Sub(last).Microseconds(), maxInterval.Microseconds())
+			assert.GreaterOrEqual(t, now.Sub(last), minInterval)
+			assert.Less(t, now.Sub(last), maxInterval)
 		}
 		last = now
 		if count >= 10 {
@@ -155,7 +163,48 @@ func TestJitter(t *testing.T) {
 		}
 		count++
 		return errTest
-	})
+	}, backoff.WithClock(clock))
 	assert.NoError(t, err)
 	assert.EqualValues(t, 10, count)
 }
+
+func TestJitterRandom(t *testing.T) {
+	p1 := backoff.ConstantBackOff(time.Second).With(backoff.JitterRandom(0.5, backoff.NewRandom(1)))
+	p2 := backoff.ConstantBackOff(time.Second).With(backoff.JitterRandom(0.5, backoff.NewRandom(1)))
+
+	delays := backoff.Schedule(p1, 10)
+	assert.Equal(t, delays, backoff.Schedule(p2, 10))
+	for _, d := range delays {
+		assert.Greater(t, d, 500*time.Millisecond)
+		assert.LessOrEqual(t, d, time.Second)
+	}
+}
+
+func TestMaxElapsedTime(t *testing.T) {
+	const interval = 300 * time.Millisecond
+
+	p := backoff.ConstantBackOff(interval).With(backoff.MaxElapsedTime(time.Second))
+
+	for i := 0; i < 3; i++ {
+		clock := backofftest.NewRecorder(start.Add(time.Duration(i) * time.Hour))
+		err := backoff.Retry(p, func() error {
+			return errTest
+		}, backoff.WithClock(clock))
+		assertRetryError(t, err, backoff.StopTimeout)
+		assert.Equal(t, []time.Duration{interval, interval, interval, 100 * time.Millisecond}, clock.Delays())
+	}
+}
+
+func TestMaxElapsedTimeSystemClock(t *testing.T) {
+	const maxElapsedTime = 20 * time.Millisecond
+
+	p := backoff.ConstantBackOff(time.Millisecond).With(backoff.MaxElapsedTime(maxElapsedTime))
+
+	time.Sleep(maxElapsedTime)
+	begin := time.Now()
+	err := backoff.Retry(p, func() error {
+		return errTest
+	})
+	assertRetryError(t, err, backoff.StopTimeout)
+	assertInterval(t, maxElapsedTime, time.Since(begin))
+}
@@ -0,0 +1,316 @@
+package backoff
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errNoSpec is returned when marshalling a backoff policy which cannot be described in the syntax of Parse.
+var errNoSpec = errors.New("backoff: policy cannot be described")
+
+type paramKind int
+
+const (
+	durationParam paramKind = iota
+	floatParam
+	intParam
+	timeParam
+)
+
+type param struct {
+	name string
+	kind paramKind
+	// check validates the parsed value in addition to the checks of its kind.
+	check func(v interface{}) error
+}
+
+// positive checks that a float value is greater than zero.
+func positive(v interface{}) error {
+	if v.(float64) <= 0 {
+		return errors.New("must be positive")
+	}
+	return nil
+}
+
+// fraction checks that a float value is within [0, 1].
+func fraction(v interface{}) error {
+	if f := v.(float64); f < 0 || f > 1 {
+		return errors.New("must be between 0 and 1")
+	}
+	return nil
+}
+
+// maxRetries checks that an int value is a valid argument for MaxRetries.
+func maxRetries(v interface{}) error {
+	if v.(int) < -1 {
+		return errors.New("must be at least -1")
+	}
+	return nil
+}
+
+// args are the parsed arguments of a policy or option in the order of its params.
+type args []interface{}
+
+func (a args) duration(i int) time.Duration { return a[i].(time.Duration) }
+func (a args) float(i int) float64          { return a[i].(float64) }
+func (a args) int(i int) int                { return a[i].(int) }
+func (a args) time(i int) time.Time         { return a[i].(time.Time) }
+
+type policyDef struct {
+	params []param
+	policy func(a args) *BackOff
+}
+
+type optionDef struct {
+	params []param
+	option func(a args) Option
+}
+
+var policyDefs = map[string]policyDef{
+	"zero": {
+		policy: func(args) *BackOff { return ZeroBackOff() },
+	},
+	"constant": {
+		params: []param{{"interval", durationParam, nil}},
+		policy: func(a args) *BackOff { return ConstantBackOff(a.duration(0)) },
+	},
+	"exponential": {
+		params: []param{{"initial", durationParam, nil}, {"factor", floatParam, positive}},
+		policy: func(a args) *BackOff { return ExponentialBackOff(a.duration(0), a.float(1)) },
+	},
+	"linear": {
+		params: []param{{"initial", durationParam, nil}, {"increment", durationParam, nil}},
+		policy: func(a args) *BackOff { return LinearBackOff(a.duration(0), a.duration(1)) },
+	},
+	"fibonacci": {
+		params: []param{{"initial", durationParam, nil}},
+		policy: func(a args) *BackOff { return FibonacciBackOff(a.duration(0)) },
+	},
+	"full_jitter": {
+		params: []param{{"base", durationParam, nil}, {"max", durationParam, nil}},
+		policy: func(a args) *BackOff { return FullJitterBackOff(a.duration(0), a.duration(1), nil) },
+	},
+	"equal_jitter": {
+		params: []param{{"base", durationParam, nil}, {"max", durationParam, nil}},
+		policy: func(a args) *BackOff { return EqualJitterBackOff(a.duration(0), a.duration(1), nil) },
+	},
+	"decorrelated_jitter": {
+		params: []param{{"base", durationParam, nil}, {"max", durationParam, nil}},
+		policy: func(a args) *BackOff { return DecorrelatedJitterBackOff(a.duration(0), a.duration(1), nil) },
+	},
+}
+
+var optionDefs = map[string]optionDef{
+	"max_retries": {
+		params: []param{{"max", intParam, maxRetries}},
+		option: func(a args) Option { return MaxRetries(a.int(0)) },
+	},
+	"max_interval": {
+		params: []param{{"max", durationParam, nil}},
+		option: func(a args) Option { return MaxInterval(a.duration(0)) },
+	},
+	"max_elapsed_time": {
+		params: []param{{"max", durationParam, nil}},
+		option: func(a args) Option { return MaxElapsedTime(a.duration(0)) },
+	},
+	"timeout": {
+		params: []param{{"time", timeParam, nil}},
+		option: func(a args) Option { return Timeout(a.time(0)) },
+	},
+	"jitter": {
+		params: []param{{"factor", floatParam, fraction}},
+		option: func(a args) Option { return Jitter(a.float(0)) },
+	},
+}
+
+// Parse parses a backoff policy followed by options separated by "|", e.g.
+//
+//	exponential(initial=100ms,factor=2) | max_interval(5s) | max_retries(6) | jitter(0.2)
+//
+// Arguments are given either by name or in order. Durations use the syntax of time.ParseDuration and times use
+// RFC 3339. The supported policies are zero, constant(interval), exponential(initial,factor),
+// linear(initial,increment), fibonacci(initial), full_jitter(base,max), equal_jitter(base,max) and
+// decorrelated_jitter(base,max). The supported options are
+// max_retries(max), max_interval(max), max_elapsed_time(max), timeout(time) and jitter(factor).
+// Durations must not be negative, the factor of exponential must be positive, the factor of jitter must be between 0
+// and 1 and max_retries must be at least -1, which means unlimited retries.
+func Parse(s string) (*BackOff, error) {
+	terms := strings.Split(s, "|")
+
+	name, values, err := parseTerm(terms[0])
+	if err != nil {
+		return nil, err
+	}
+	pdef, ok := policyDefs[name]
+	if !ok {
+		return nil, errors.New("backoff: unknown policy " + strconv.Quote(name))
+	}
+	a, err := parseArgs(name, pdef.params, values)
+	if err != nil {
+		return nil, err
+	}
+	b := pdef.policy(a)
+
+	opts := make([]Option, 0, len(terms)-1)
+	for _, term := range terms[1:] {
+		name, values, err := parseTerm(term)
+		if err != nil {
+			return nil, err
+		}
+		odef, ok := optionDefs[name]
+		if !ok {
+			return nil, errors.New("backoff: unknown option " + strconv.Quote(name))
+		}
+		a, err := parseArgs(name, odef.params, values)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, odef.option(a))
+	}
+	return b.With(opts...), nil
+}
+
+// parseTerm splits a term like name(a,b=c) into its name and arguments.
+func parseTerm(term string) (string, []string, error) {
+	term = strings.TrimSpace(term)
+	open := strings.IndexByte(term, '(')
+	if open < 0 {
+		if term == "" {
+			return "", nil, errors.New("backoff: missing policy or option")
+		}
+		return term, nil, nil
+	}
+	if !strings.HasSuffix(term, ")") {
+		return "", nil, errors.New("backoff: missing closing parenthesis in " + strconv.Quote(term))
+	}
+	name := strings.TrimSpace(term[:open])
+	inner := strings.TrimSpace(term[open+1 : len(term)-1])
+	if inner == "" {
+		return name, nil, nil
+	}
+	values := strings.Split(inner, ",")
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return name, values, nil
+}
+
+// parseArgs parses the named or positional argument values of the given params.
+func parseArgs(name string, params []param, values []string) (args, error) {
+	if len(values) > len(params) {
+		return nil, errors.New("backoff: too many arguments for " + strconv.Quote(name))
+	}
+	raw := make([]string, len(params))
+	set := make([]bool, len(params))
+	for i, value := range values {
+		j := i
+		if key, v, ok := strings.Cut(value, "="); ok {
+			j = -1
+			for k, p := range params {
+				if p.name == strings.TrimSpace(key) {
+					j = k
+				}
+			}
+			if j < 0 {
+				return nil, errors.New("backoff: unknown argument " + strconv.Quote(key) + " for " + strconv.Quote(name))
+			}
+			value = strings.TrimSpace(v)
+		}
+		if set[j] {
+			return nil, errors.New("backoff: duplicate argument " + strconv.Quote(params[j].name) + " for " +
+				strconv.Quote(name))
+		}
+		raw[j], set[j] = value, true
+	}
+
+	a := make(args, len(params))
+	for i, p := range params {
+		if !set[i] {
+			return nil, errors.New("backoff: missing argument " + strconv.Quote(p.name) + " for " + strconv.Quote(name))
+		}
+		v, err := parseValue(p.kind, raw[i])
+		if err == nil && p.check != nil {
+			err = p.check(v)
+		}
+		if err != nil {
+			return nil, errors.New("backoff: invalid argument " + strconv.Quote(p.name) + " for " + strconv.Quote(name) +
+				": " + err.Error())
+		}
+		a[i] = v
+	}
+	return a, nil
+}
+
+func parseValue(kind paramKind, value string) (interface{}, error) {
+	switch kind {
+	case durationParam:
+		d, err := time.ParseDuration(value)
+		if err == nil && d < 0 {
+			return nil, errors.New("must not be negative")
+		}
+		return d, err
+	case floatParam:
+		return strconv.ParseFloat(value, 64)
+	case intParam:
+		return strconv.Atoi(value)
+	case timeParam:
+		return time.Parse(time.RFC3339Nano, value)
+	}
+	panic("unknown parameter kind")
+}
+
+// MarshalText describes the backoff policy in the syntax of Parse. It fails for policies and options that cannot be
+// described, such as Cancel, Budget or custom policies.
+func (b *BackOff) MarshalText() ([]byte, error) {
+	s, ok := specOf(b.Policy)
+	if !ok {
+		return nil, errNoSpec
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalText parses a backoff policy in the syntax of Parse.
+func (b *BackOff) UnmarshalText(text []byte) error {
+	p, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	b.Policy = p.Policy
+	return nil
+}
+
+// String describes the backoff policy in the syntax of Parse if possible.
+func (b *BackOff) String() string {
+	if s, ok := specOf(b.Policy); ok {
+		return s
+	}
+	return "backoff.BackOff"
+}
+
+// specPolicy is implemented by policies and options that can be described in the syntax of Parse.
+type specPolicy interface {
+	spec() (string, bool)
+}
+
+// specOf describes p in the syntax of Parse.
+func specOf(p Policy) (string, bool) {
+	if sp, ok := p.(specPolicy); ok {
+		return sp.spec()
+	}
+	return "", false
+}
+
+// optionSpec describes an option wrapping the delegate.
+func optionSpec(delegate Policy, option string) (string, bool) {
+	s, ok := specOf(delegate)
+	if !ok || option == "" {
+		return "", false
+	}
+	return s + " | " + option, true
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
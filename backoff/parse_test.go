@@ -0,0 +1,118 @@
+package backoff_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParse(t *testing.T) {
+	p, err := backoff.Parse("exponential(initial=100ms,factor=2) | max_interval(500ms) | max_retries(5)")
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		500 * time.Millisecond,
+		500 * time.Millisecond,
+	}, backoff.Schedule(p, 10))
+}
+
+func TestParseArguments(t *testing.T) {
+	tests := map[string]string{
+		"zero":                                   "zero",
+		"constant(1s)":                           "constant(1s)",
+		" constant( interval = 1m ) ":            "constant(1m0s)",
+		"exponential(1s, 1.5)":                   "exponential(initial=1s,factor=1.5)",
+		"exponential(factor=3,initial=2s)":       "exponential(initial=2s,factor=3)",
+		"full_jitter(10ms,1s)|jitter(factor=.5)": "full_jitter(base=10ms,max=1s) | jitter(0.5)",
+		"equal_jitter(base=1s,max=1m)":           "equal_jitter(base=1s,max=1m0s)",
+		"decorrelated_jitter(1s,1m)":             "decorrelated_jitter(base=1s,max=1m0s)",
+		"linear(1s,increment=500ms)":             "linear(initial=1s,increment=500ms)",
+		"fibonacci(initial=1s)":                  "fibonacci(1s)",
+		"zero | max_elapsed_time(1h)":            "zero | max_elapsed_time(1h0m0s)",
+		"zero | timeout(2022-01-01T00:00:00Z)":   "zero | timeout(2022-01-01T00:00:00Z)",
+	}
+	for s, expected := range tests {
+		t.Run(s, func(t *testing.T) {
+			p, err := backoff.Parse(s)
+			if assert.NoError(t, err) {
+				assert.Equal(t, expected, p.String())
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"":                           "backoff: missing policy or option",
+		"quadratic(1s)":              `backoff: unknown policy "quadratic"`,
+		"max_retries(3)":             `backoff: unknown policy "max_retries"`,
+		"zero | retries(3)":          `backoff: unknown option "retries"`,
+		"zero | exponential(1s,2)":   `backoff: unknown option "exponential"`,
+		"zero |":                     "backoff: missing policy or option",
+		"constant(1s":                `backoff: missing closing parenthesis in "constant(1s"`,
+		"constant()":                 `backoff: missing argument "interval" for "constant"`,
+		"constant(1s,2s)":            `backoff: too many arguments for "constant"`,
+		"constant(delay=1s)":         `backoff: unknown argument "delay" for "constant"`,
+		"exponential(1s,initial=2s)": `backoff: duplicate argument "initial" for "exponential"`,
+		"constant(1)": `backoff: invalid argument "interval" for "constant": ` +
+			`time: missing unit in duration "1"`,
+		"zero | max_retries(many)": `backoff: invalid argument "max" for "max_retries": ` +
+			`strconv.Atoi: parsing "many": invalid syntax`,
+		"exponential(initial=1s,factor=x2)": `backoff: invalid argument "factor" for "exponential": ` +
+			`strconv.ParseFloat: parsing "x2": invalid syntax`,
+		"constant(-1ns)":               `backoff: invalid argument "interval" for "constant": must not be negative`,
+		"exponential(-1s,2)":           `backoff: invalid argument "initial" for "exponential": must not be negative`,
+		"exponential(1s,0)":            `backoff: invalid argument "factor" for "exponential": must be positive`,
+		"full_jitter(1s,-1m)":          `backoff: invalid argument "max" for "full_jitter": must not be negative`,
+		"zero | jitter(5)":             `backoff: invalid argument "factor" for "jitter": must be between 0 and 1`,
+		"zero | jitter(-0.1)":          `backoff: invalid argument "factor" for "jitter": must be between 0 and 1`,
+		"zero | max_retries(-7)":       `backoff: invalid argument "max" for "max_retries": must be at least -1`,
+		"zero | max_elapsed_time(-1s)": `backoff: invalid argument "max" for "max_elapsed_time": must not be negative`,
+	}
+	for s, expected := range tests {
+		t.Run(s, func(t *testing.T) {
+			_, err := backoff.Parse(s)
+			assert.EqualError(t, err, expected)
+		})
+	}
+}
+
+type config struct {
+	Retry *backoff.BackOff `json:"retry" yaml:"retry"`
+}
+
+func TestBackOffJSON(t *testing.T) {
+	var c config
+	err := json.Unmarshal([]byte(`{"retry": "constant(1s) | max_retries(2)"}`), &c)
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{time.Second, time.Second}, backoff.Schedule(c.Retry, 10))
+
+	data, err := json.Marshal(c)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"retry": "constant(1s) | max_retries(2)"}`, string(data))
+
+	err = json.Unmarshal([]byte(`{"retry": "constant(1s) | max_tries(2)"}`), &c)
+	assert.EqualError(t, err, `backoff: unknown option "max_tries"`)
+
+	_, err = json.Marshal(config{Retry: backoff.ZeroBackOff().With(backoff.Cancel(context.Background()))})
+	assert.Error(t, err)
+}
+
+func TestBackOffYAML(t *testing.T) {
+	var c config
+	err := yaml.Unmarshal([]byte("retry: exponential(initial=1s,factor=2) | max_retries(2)"), &c)
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, backoff.Schedule(c.Retry, 10))
+
+	data, err := yaml.Marshal(c)
+	assert.NoError(t, err)
+	assert.Equal(t, "retry: exponential(initial=1s,factor=2) | max_retries(2)\n", string(data))
+}
@@ -0,0 +1,92 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ErrNotDone is the error of a Poll attempt whose condition is not yet true. It is passed to the functions given with
+// OnRetry and can be matched with errors.Is on the error returned by Poll.
+var ErrNotDone = errors.New("backoff: condition not done")
+
+// PollError is returned by Poll if the condition did not become true. It unwraps to a RetryError with the errors of
+// all attempts.
+type PollError struct {
+	// Err is the error returned by the last check of the condition, or nil if the condition was just not yet true.
+	// If Reason is StopPermanent, it is the error wrapped with Permanent.
+	Err error
+
+	// Value is the value observed by the last check of a condition given to PollWithData. It is nil for Poll.
+	Value interface{}
+
+	// Attempts is the number of times the condition was checked.
+	Attempts int
+
+	// Elapsed is the time passed between the first check and giving up.
+	Elapsed time.Duration
+
+	// Reason describes why the condition was not checked again.
+	Reason StopReason
+
+	retryErr *RetryError
+}
+
+func (e *PollError) Error() string {
+	s := "backoff: condition not met after " + strconv.Itoa(e.Attempts) + " attempts in " + e.Elapsed.String() +
+		" (" + e.Reason.String() + ")"
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+func (e *PollError) Unwrap() error {
+	return e.retryErr
+}
+
+// Poll checks condition according to the backoff policy until it returns true. Returning false with a nil error means
+// that the condition is not yet true. Other errors are treated as failed checks and are retried as well, unless they
+// are wrapped with Permanent. If the condition does not become true before the policy stops or the context is done,
+// a PollError is returned.
+func Poll(ctx context.Context, p Policy, condition func(ctx context.Context) (bool, error), opts ...RetryOption) error {
+	_, err := PollWithData(ctx, p, func(ctx context.Context) (interface{}, bool, error) {
+		done, err := condition(ctx)
+		return nil, done, err
+	}, opts...)
+	return err
+}
+
+// PollWithData works like Poll but for conditions observing a value, e.g. the status of a job. The value of the last
+// check is returned, even if the condition did not become true, and is also stored in the Value of the PollError.
+func PollWithData[T any](ctx context.Context, p Policy, condition func(ctx context.Context) (T, bool, error),
+	opts ...RetryOption) (T, error) {
+	v, err := retry(ctx, p, func(ctx context.Context) (T, error) {
+		v, done, err := condition(ctx)
+		if err == nil && !done {
+			err = ErrNotDone
+		}
+		return v, err
+	}, opts)
+
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		return v, err
+	}
+	last := retryErr.Errors[len(retryErr.Errors)-1]
+	if retryErr.Reason == StopPermanent {
+		last = retryErr.Err
+	}
+	if last == ErrNotDone {
+		last = nil
+	}
+	return v, &PollError{
+		Err:      last,
+		Value:    v,
+		Attempts: retryErr.Attempts,
+		Elapsed:  retryErr.Elapsed,
+		Reason:   retryErr.Reason,
+		retryErr: retryErr,
+	}
+}
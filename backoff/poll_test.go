@@ -0,0 +1,120 @@
+package backoff_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+	"github.com/ireward/wago/backoff/backofftest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func assertPollError(t *testing.T, err error, reason backoff.StopReason) *backoff.PollError {
+	var pollErr *backoff.PollError
+	if assert.ErrorAs(t, err, &pollErr) {
+		assert.Equal(t, reason, pollErr.Reason)
+		return pollErr
+	}
+	return &backoff.PollError{}
+}
+
+func TestPoll(t *testing.T) {
+	clock := backofftest.NewRecorder(start)
+
+	var count int
+	err := backoff.Poll(context.Background(), backoff.ConstantBackOff(time.Second), func(context.Context) (bool, error) {
+		count++
+		switch count {
+		case 1:
+			return false, nil
+		case 2:
+			return false, errTest
+		}
+		return true, nil
+	}, backoff.WithClock(clock))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, []time.Duration{time.Second, time.Second}, clock.Delays())
+}
+
+func TestPollNotDone(t *testing.T) {
+	clock := backofftest.NewRecorder(start)
+	p := backoff.ConstantBackOff(time.Second).With(backoff.MaxElapsedTime(3 * time.Second))
+
+	var notified []error
+	err := backoff.Poll(context.Background(), p, func(context.Context) (bool, error) {
+		return false, nil
+	}, backoff.WithClock(clock), backoff.OnRetry(func(attempt int, err error, next time.Duration) {
+		notified = append(notified, err)
+	}))
+	pollErr := assertPollError(t, err, backoff.StopTimeout)
+	assert.NoError(t, pollErr.Err)
+	assert.Nil(t, pollErr.Value)
+	assert.Equal(t, 4, pollErr.Attempts)
+	assert.Equal(t, 3*time.Second, pollErr.Elapsed)
+	assert.True(t, errors.Is(err, backoff.ErrNotDone))
+	assert.Equal(t, []error{backoff.ErrNotDone, backoff.ErrNotDone, backoff.ErrNotDone}, notified)
+	assert.EqualError(t, err, "backoff: condition not met after 4 attempts in 3s (timeout)")
+}
+
+func TestPollLastError(t *testing.T) {
+	err := backoff.Poll(context.Background(), backoff.ZeroBackOff().With(backoff.MaxRetries(1)),
+		func(context.Context) (bool, error) {
+			return false, errTest
+		})
+	pollErr := assertPollError(t, err, backoff.StopMaxRetries)
+	assert.Equal(t, errTest, pollErr.Err)
+	assert.True(t, errors.Is(err, errTest))
+	assert.EqualError(t, err, "backoff: condition not met after 2 attempts in "+pollErr.Elapsed.String()+
+		" (max retries reached): test")
+}
+
+func TestPollPermanentError(t *testing.T) {
+	var count int
+	err := backoff.Poll(context.Background(), backoff.ZeroBackOff(), func(context.Context) (bool, error) {
+		count++
+		return false, backoff.Permanent(errTest)
+	})
+	assert.Equal(t, errTest, assertPollError(t, err, backoff.StopPermanent).Err)
+	assert.Equal(t, 1, count)
+}
+
+func TestPollContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	clock := backofftest.NewFakeClock(start)
+
+	go func() {
+		clock.BlockUntil(1)
+		cancel()
+	}()
+
+	err := backoff.Poll(ctx, backoff.ConstantBackOff(time.Hour), func(context.Context) (bool, error) {
+		return false, nil
+	}, backoff.WithClock(clock))
+	assertPollError(t, err, backoff.StopContext)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestPollWithData(t *testing.T) {
+	p := backoff.ZeroBackOff().With(backoff.MaxRetries(2))
+
+	var count int
+	status, err := backoff.PollWithData(context.Background(), p, func(context.Context) (string, bool, error) {
+		count++
+		if count < 2 {
+			return "pending", false, nil
+		}
+		return "done", true, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "done", status)
+
+	status, err = backoff.PollWithData(context.Background(), p, func(context.Context) (string, bool, error) {
+		return "running", false, nil
+	})
+	assert.Equal(t, "running", assertPollError(t, err, backoff.StopMaxRetries).Value)
+	assert.Equal(t, "running", status)
+}
@@ -0,0 +1,65 @@
+package backoff
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// Random is a source of pseudo-random numbers used by jittered policies and options. Implementations must be safe for
+// concurrent use, since a policy may be shared by multiple invocations of Retry.
+type Random interface {
+	// Float64 returns a pseudo-random number in the interval [0.0, 1.0).
+	Float64() float64
+}
+
+// defaultRandom is the Random used if none is given.
+var defaultRandom Random = &pooledRandom{
+	pool: sync.Pool{
+		New: func() interface{} {
+			return rand.New(rand.NewSource(rand.Int63()))
+		},
+	},
+}
+
+// DefaultRandom returns the Random used by jittered policies and options if none is given. Unlike the default source
+// of the math/rand package, it does not contend on a single lock when used concurrently.
+func DefaultRandom() Random {
+	return defaultRandom
+}
+
+// NewRandom returns a Random producing the same sequence of numbers for the same seed. It is safe for concurrent use,
+// but the sequence is only reproducible if the numbers are taken in a deterministic order, e.g. in unit tests.
+func NewRandom(seed int64) Random {
+	return &lockedRandom{r: rand.New(rand.NewSource(seed))}
+}
+
+// pooledRandom keeps a pool of randomly seeded generators, so concurrent callers rarely share a generator.
+type pooledRandom struct {
+	pool sync.Pool
+}
+
+func (r *pooledRandom) Float64() float64 {
+	g := r.pool.Get().(*rand.Rand)
+	defer r.pool.Put(g)
+	return g.Float64()
+}
+
+// lockedRandom guards a single generator with a mutex.
+type lockedRandom struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func (r *lockedRandom) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.Float64()
+}
+
+// orDefault returns r, or the default Random if r is nil.
+func orDefault(r Random) Random {
+	if r == nil {
+		return defaultRandom
+	}
+	return r
+}
@@ -0,0 +1,43 @@
+package backoff_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ireward/wago/backoff"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRandom(t *testing.T) {
+	r1 := backoff.NewRandom(7)
+	r2 := backoff.NewRandom(7)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, r1.Float64(), r2.Float64())
+	}
+}
+
+func TestRandomConcurrent(t *testing.T) {
+	const parallelism = 8
+
+	for name, r := range map[string]backoff.Random{
+		"default": backoff.DefaultRandom(),
+		"seeded":  backoff.NewRandom(1),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			wg.Add(parallelism)
+			for i := 0; i < parallelism; i++ {
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 1000; j++ {
+						f := r.Float64()
+						assert.GreaterOrEqual(t, f, 0.0)
+						assert.Less(t, f, 1.0)
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package backoff
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Limiter limits the rate of operations, see RateLimit.
+type Limiter interface {
+	// Wait blocks until the next operation is allowed or the context is done.
+	Wait(ctx context.Context) error
+}
+
+// A RetryOption configures a single invocation of Retry.
+type RetryOption interface {
+	applyRetry(o *retryOptions)
+}
+
+// retryOptionFunc wraps a func so it satisfies the RetryOption interface.
+type retryOptionFunc func(*retryOptions)
+
+func (f retryOptionFunc) applyRetry(o *retryOptions) {
+	f(o)
+}
+
+type retryOptions struct {
+	retryResult    func(v interface{}) bool
+	resultType     reflect.Type
+	notify         []Notify
+	clock          Clock
+	attemptTimeout time.Duration
+	maxConcurrency int
+	limiter        Limiter
+
+	// discard releases values of attempts that are not returned to the caller, e.g. the responses of abandoned
+	// attempts. It is used internally by Transport.
+	discard func(v interface{})
+}
+
+func newRetryOptions(opts []RetryOption) *retryOptions {
+	o := &retryOptions{clock: SystemClock()}
+	for _, opt := range opts {
+		opt.applyRetry(o)
+	}
+	return o
+}
+
+// discardValue releases v if a discard function is configured.
+func (o *retryOptions) discardValue(v interface{}) {
+	if o.discard != nil {
+		o.discard(v)
+	}
+}
+
+// checkResultType returns ErrResultType if the predicate given with RetryOnResult does not accept values of type T.
+func checkResultType[T any](o *retryOptions) error {
+	if o.resultType == nil {
+		return nil
+	}
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if o.resultType == t || (t.Kind() == reflect.Interface && o.resultType.Implements(t)) {
+		return nil
+	}
+	return fmt.Errorf("%w %s", ErrResultType, t)
+}
+
+// RetryOnResult configures Retry to treat a successful attempt as failed if the returned value satisfies retry.
+// Such attempts are retried according to the backoff policy. If the policy stops, ErrUnacceptableResult is returned
+// together with the last value. T must be the type of the values returned by the operation, otherwise ErrResultType
+// is returned without calling the operation. If the operation returns an interface type that T implements, retry is
+// only called for values of type T.
+func RetryOnResult[T any](retry func(v T) bool) RetryOption {
+	return retryOptionFunc(func(o *retryOptions) {
+		o.retryResult = func(v interface{}) bool {
+			t, ok := v.(T)
+			return ok && retry(t)
+		}
+		o.resultType = reflect.TypeOf((*T)(nil)).Elem()
+	})
+}
+
+// OnRetry configures Retry to call notify after every failed attempt that is going to be retried.
+// OnRetry can be given multiple times, the functions are called in the given order.
+func OnRetry(notify Notify) RetryOption {
+	return retryOptionFunc(func(o *retryOptions) {
+		o.notify = append(o.notify, notify)
+	})
+}
+
+// WithClock configures Retry to use the given Clock for waiting between attempts, for AttemptTimeout and for policies
+// depending on the current time, such as Timeout.
+func WithClock(c Clock) RetryOption {
+	return retryOptionFunc(func(o *retryOptions) {
+		o.clock = c
+	})
+}
+
+// AttemptTimeout configures Retry to bound the duration of every single attempt. Each attempt receives a context that
+// is done after d. If an attempt does not return in time, it is abandoned and retried with ErrAttemptTimeout as its
+// error. Abandoned attempts continue to run in the background until they return, so they must respect the context.
+func AttemptTimeout(d time.Duration) RetryOption {
+	return retryOptionFunc(func(o *retryOptions) {
+		o.attemptTimeout = d
+	})
+}
+
+// MaxConcurrentAttempts configures Hedge to run at most n attempts concurrently. Further attempts are started when
+// a running attempt fails.
+func MaxConcurrentAttempts(n int) RetryOption {
+	return retryOptionFunc(func(o *retryOptions) {
+		o.maxConcurrency = n
+	})
+}
+
+// RateLimit configures Retry to wait for the limiter before every attempt, so that attempts respect the rate limit.
+// If waiting fails, e.g. because the context is done, the error is treated like an error returned by the attempt.
+func RateLimit(l Limiter) RetryOption {
+	return retryOptionFunc(func(o *retryOptions) {
+		o.limiter = l
+	})
+}
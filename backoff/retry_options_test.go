@@ -0,0 +1,223 @@
+package backoff_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+	"github.com/ireward/wago/backoff/backofftest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryOnResult(t *testing.T) {
+	const pending, done = "pending", "done"
+
+	var count uint
+	v, err := backoff.RetryWithData(backoff.ZeroBackOff(), func() (string, error) {
+		if count >= retryCount {
+			return done, nil
+		}
+		count++
+		return pending, nil
+	}, backoff.RetryOnResult(func(status string) bool {
+		return status == pending
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, done, v)
+	assert.EqualValues(t, retryCount, count)
+}
+
+func TestRetryOnResultExhausted(t *testing.T) {
+	const retries = 3
+
+	var count uint
+	v, err := backoff.RetryWithData(backoff.ZeroBackOff().With(backoff.MaxRetries(retries)), func() (int, error) {
+		count++
+		return int(count), nil
+	}, backoff.RetryOnResult(func(int) bool {
+		return true
+	}))
+	assert.True(t, errors.Is(err, backoff.ErrUnacceptableResult))
+	assert.Equal(t, retries+1, v)
+	assert.EqualValues(t, retries+1, count)
+}
+
+func TestRetryOnResultPermanentError(t *testing.T) {
+	var count uint
+	_, err := backoff.RetryWithData(backoff.ZeroBackOff(), func() (int, error) {
+		count++
+		if count > 1 {
+			return 0, backoff.Permanent(errTest)
+		}
+		return 0, nil
+	}, backoff.RetryOnResult(func(v int) bool {
+		return v == 0
+	}))
+	assert.Equal(t, errTest, assertRetryError(t, err, backoff.StopPermanent).Err)
+	assert.EqualValues(t, 2, count)
+}
+
+func TestRetryOnResultOtherType(t *testing.T) {
+	var count uint
+	_, err := backoff.RetryWithData(backoff.ZeroBackOff(), func() (int, error) {
+		count++
+		return 0, nil
+	}, backoff.RetryOnResult(func(int64) bool {
+		return true
+	}))
+	assert.ErrorIs(t, err, backoff.ErrResultType)
+	assert.EqualError(t, err, "backoff: RetryOnResult does not accept the result type int")
+	assert.EqualValues(t, 0, count)
+
+	err = backoff.Retry(backoff.ZeroBackOff(), func() error {
+		count++
+		return nil
+	}, backoff.RetryOnResult(func(string) bool {
+		return true
+	}))
+	assert.ErrorIs(t, err, backoff.ErrResultType)
+	assert.EqualValues(t, 0, count)
+}
+
+func TestRetryOnResultInterface(t *testing.T) {
+	var count int
+	v, err := backoff.RetryWithData(backoff.ZeroBackOff(), func() (interface{}, error) {
+		count++
+		if count < 3 {
+			return "pending", nil
+		}
+		return count, nil
+	}, backoff.RetryOnResult(func(status string) bool {
+		return status == "pending"
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+
+	_, err = backoff.RetryWithData(backoff.ZeroBackOff(), func() (error, error) {
+		return nil, nil
+	}, backoff.RetryOnResult(func(string) bool {
+		return true
+	}))
+	assert.EqualError(t, err, "backoff: RetryOnResult does not accept the result type error")
+}
+
+func TestAttemptTimeout(t *testing.T) {
+	const timeout = time.Second
+	clock := backofftest.NewFakeClock(start)
+
+	started := make(chan struct{})
+	go func() {
+		for i := 0; i < 2; i++ {
+			<-started
+			clock.BlockUntil(1)
+			clock.Advance(timeout)
+		}
+	}()
+	var count int32
+	err := backoff.RetryContext(context.Background(), backoff.ZeroBackOff(), func(ctx context.Context) error {
+		if atomic.AddInt32(&count, 1) < 3 {
+			started <- struct{}{}
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		deadline, ok := ctx.Deadline()
+		assert.True(t, ok)
+		assert.Equal(t, clock.Now().Add(timeout), deadline)
+		return nil
+	}, backoff.AttemptTimeout(timeout), backoff.WithClock(clock))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, count)
+}
+
+func TestAttemptTimeoutAbandoned(t *testing.T) {
+	const timeout = time.Second
+	clock := backofftest.NewFakeClock(start)
+
+	block := make(chan struct{})
+	defer close(block)
+
+	go func() {
+		for i := 0; i < 2; i++ {
+			clock.BlockUntil(1)
+			clock.Advance(timeout)
+		}
+	}()
+	var errs []error
+	err := backoff.RetryContext(context.Background(), backoff.ZeroBackOff().With(backoff.MaxRetries(1)),
+		func(context.Context) error {
+			<-block
+			return nil
+		}, backoff.AttemptTimeout(timeout), backoff.WithClock(clock),
+		backoff.OnRetry(func(_ int, err error, _ time.Duration) {
+			errs = append(errs, err)
+		}))
+	assert.ErrorIs(t, err, backoff.ErrAttemptTimeout)
+	assert.Equal(t, []error{backoff.ErrAttemptTimeout}, errs)
+}
+
+func TestAttemptTimeoutSuccess(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attemptCtx, err := backoff.RetryWithDataContext(ctx, backoff.ZeroBackOff(),
+		func(ctx context.Context) (context.Context, error) {
+			return ctx, nil
+		}, backoff.AttemptTimeout(time.Hour), backoff.WithClock(backofftest.NewFakeClock(start)))
+	assert.NoError(t, err)
+
+	// The context of the successful attempt is neither cancelled nor tied to its parent anymore.
+	cancel()
+	assert.NoError(t, attemptCtx.Err())
+}
+
+func TestAttemptTimeoutContext(t *testing.T) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer ctxCancel()
+
+	_, err := backoff.RetryWithDataContext(ctx, backoff.ZeroBackOff(), func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 1, ctx.Err()
+	}, backoff.AttemptTimeout(time.Hour))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, backoff.ErrAttemptTimeout)
+}
+
+type countingLimiter struct {
+	waits int
+	err   error
+}
+
+func (l *countingLimiter) Wait(context.Context) error {
+	l.waits++
+	return l.err
+}
+
+func TestRateLimit(t *testing.T) {
+	l := &countingLimiter{}
+
+	var count uint
+	err := backoff.Retry(backoff.ZeroBackOff(), func() error {
+		count++
+		if count < 3 {
+			return errTest
+		}
+		return nil
+	}, backoff.RateLimit(l))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, l.waits)
+}
+
+func TestRateLimitError(t *testing.T) {
+	l := &countingLimiter{err: errTest}
+
+	var count uint
+	err := backoff.Retry(backoff.ZeroBackOff().With(backoff.MaxRetries(2)), func() error {
+		count++
+		return nil
+	}, backoff.RateLimit(l))
+	assert.ErrorIs(t, err, errTest)
+	assert.Equal(t, 3, l.waits)
+	assert.EqualValues(t, 0, count)
+}
@@ -0,0 +1,40 @@
+package retryqueue
+
+import (
+	"github.com/ireward/wago/backoff"
+)
+
+// An Option configures a Queue.
+type Option interface {
+	apply(q *Queue)
+}
+
+// optionFunc wraps a func so it satisfies the Option interface.
+type optionFunc func(*Queue)
+
+func (f optionFunc) apply(q *Queue) {
+	f(q)
+}
+
+// Workers configures the maximum number of tasks executed concurrently. It must be positive; the default is 1.
+func Workers(n int) Option {
+	return optionFunc(func(q *Queue) {
+		q.workers = n
+	})
+}
+
+// OnDeadLetter configures a function that is called for every task moved to the dead-letter file. It is called by
+// the worker that executed the task without locking the queue, so it may use the queue.
+func OnDeadLetter(f func(t Task)) Option {
+	return optionFunc(func(q *Queue) {
+		q.onDeadLetter = f
+	})
+}
+
+// WithClock configures the queue to use the given Clock for scheduling tasks and for the instances of the backoff
+// policy.
+func WithClock(c backoff.Clock) Option {
+	return optionFunc(func(q *Queue) {
+		q.clock = c
+	})
+}
@@ -0,0 +1,385 @@
+// Package retryqueue implements a durable retry queue. Tasks are persisted in a directory until they are executed
+// successfully, so they survive restarts of the process. Failed tasks are retried according to a backoff policy, and
+// tasks for which the policy stops are moved to a dead-letter file.
+//
+// Tasks are executed at least once: a task that was running when the process stopped is executed again after the
+// restart, so handlers should be idempotent.
+//
+// All pending tasks are stored in a single file, which is rewritten and synced on every Enqueue and after every
+// execution while other operations wait. The cost of each operation therefore grows with the number of pending tasks,
+// so the queue is meant for backlogs of up to a few thousand tasks.
+package retryqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+)
+
+const (
+	tasksFile       = "tasks.json"
+	deadLettersFile = "dead_letters.json"
+)
+
+// ErrNoHandler is the error of a task for whose kind no handler is registered. Such tasks are moved to the dead-letter
+// file.
+var ErrNoHandler = errors.New("retryqueue: no handler registered")
+
+// ErrInvalidWorkers is returned by Open if the number of workers configured with Workers is not positive.
+var ErrInvalidWorkers = errors.New("retryqueue: number of workers must be positive")
+
+// Handler executes tasks of a kind. Returning an error wrapped with backoff.Permanent moves the task to the
+// dead-letter file without retrying it.
+type Handler func(ctx context.Context, payload []byte) error
+
+// Task is a task stored in a Queue.
+type Task struct {
+	// ID identifies the task.
+	ID string `json:"id"`
+
+	// Kind selects the Handler executing the task.
+	Kind string `json:"kind"`
+
+	// Payload is passed to the handler.
+	Payload []byte `json:"payload"`
+
+	// Attempts is the number of failed executions.
+	Attempts int `json:"attempts"`
+
+	// Due is the time of the next execution.
+	Due time.Time `json:"due"`
+
+	// Created is the time the task was enqueued.
+	Created time.Time `json:"created"`
+
+	// LastError is the error message of the last failed execution.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Queue is a durable retry queue stored in a directory. It is safe for concurrent use, but a directory must only be
+// used by a single Queue at a time.
+type Queue struct {
+	dir          string
+	policy       backoff.Policy
+	workers      int
+	onDeadLetter func(t Task)
+	clock        backoff.Clock
+
+	mu          sync.Mutex
+	handlers    map[string]Handler
+	tasks       map[string]*Task
+	policies    map[string]backoff.Policy
+	running     map[string]bool
+	deadLetters []Task
+	wake        chan struct{}
+}
+
+// Open opens the queue stored in dir, creating the directory if needed. Failed tasks are retried according to the
+// backoff policy p. The state of the policy is not persisted, but restored after a restart by calling NextBackOff
+// once for every failed execution, so options depending on the time, such as MaxElapsedTime, start over.
+func Open(dir string, p backoff.Policy, opts ...Option) (*Queue, error) {
+	q := &Queue{
+		dir:      dir,
+		policy:   p,
+		workers:  1,
+		clock:    backoff.SystemClock(),
+		handlers: make(map[string]Handler),
+		tasks:    make(map[string]*Task),
+		policies: make(map[string]backoff.Policy),
+		running:  make(map[string]bool),
+		wake:     make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt.apply(q)
+	}
+	if q.workers <= 0 {
+		return nil, ErrInvalidWorkers
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	tasks, err := readTasks(q.path(tasksFile))
+	if err != nil {
+		return nil, err
+	}
+	for i := range tasks {
+		t := tasks[i]
+		q.tasks[t.ID] = &t
+	}
+	if q.deadLetters, err = readTasks(q.path(deadLettersFile)); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// Handle registers the handler for tasks of the given kind. Handlers must be registered before calling Run.
+func (q *Queue) Handle(kind string, h Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[kind] = h
+}
+
+// Enqueue stores a task of the given kind and returns its ID. The task is persisted when Enqueue returns and is
+// executed as soon as possible by Run.
+func (q *Queue) Enqueue(kind string, payload []byte) (string, error) {
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+	now := q.clock.Now()
+	t := &Task{
+		ID:      id,
+		Kind:    kind,
+		Payload: payload,
+		Due:     now,
+		Created: now,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.tasks[id] = t
+	if err := q.saveTasks(); err != nil {
+		delete(q.tasks, id)
+		return "", err
+	}
+	q.notify()
+	return id, nil
+}
+
+// Pending returns the tasks that have not been executed successfully yet, ordered by due time.
+func (q *Queue) Pending() []Task {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.sortedTasks()
+}
+
+// DeadLetters returns the tasks moved to the dead-letter file, in the order they were moved.
+func (q *Queue) DeadLetters() []Task {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]Task(nil), q.deadLetters...)
+}
+
+// Run executes due tasks with at most the configured number of workers until the context is done. It returns the
+// context error after all running tasks have returned. Tasks interrupted by the context are not counted as failed.
+// Errors persisting the queue are returned immediately.
+func (q *Queue) Run(ctx context.Context) error {
+	var (
+		wg      sync.WaitGroup
+		slots   = make(chan struct{}, q.workers)
+		errOnce sync.Once
+		runErr  error
+	)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	fail := func(err error) {
+		errOnce.Do(func() {
+			runErr = err
+			cancel()
+		})
+	}
+
+	for {
+		t, wait := q.next()
+		if t != nil {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				q.mu.Lock()
+				delete(q.running, t.ID)
+				q.mu.Unlock()
+				wg.Wait()
+				return q.runError(ctx, runErr)
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-slots }()
+				if err := q.execute(ctx, *t); err != nil {
+					fail(err)
+				}
+			}()
+			continue
+		}
+
+		var timer backoff.Timer
+		var fired <-chan time.Time
+		if wait >= 0 {
+			timer = q.clock.NewTimer(wait)
+			fired = timer.C()
+		}
+		select {
+		case <-fired:
+		case <-q.wake:
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			wg.Wait()
+			return q.runError(ctx, runErr)
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+func (q *Queue) runError(ctx context.Context, err error) error {
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// next marks the next due task as running and returns it. Otherwise, it returns the duration until the next task is
+// due, or a negative duration if there are no tasks waiting.
+func (q *Queue) next() (*Task, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.clock.Now()
+	var next *Task
+	for _, t := range q.tasks {
+		if q.running[t.ID] {
+			continue
+		}
+		if next == nil || t.Due.Before(next.Due) {
+			next = t
+		}
+	}
+	if next == nil {
+		return nil, -1
+	}
+	if next.Due.After(now) {
+		return nil, next.Due.Sub(now)
+	}
+	q.running[next.ID] = true
+	t := *next
+	return &t, 0
+}
+
+// execute runs the handler of t and records the outcome.
+func (q *Queue) execute(ctx context.Context, t Task) error {
+	q.mu.Lock()
+	h := q.handlers[t.Kind]
+	q.mu.Unlock()
+
+	err := ErrNoHandler
+	if h != nil {
+		err = h(ctx, t.Payload)
+	}
+
+	deadLetter, err := q.record(ctx, t, err)
+	if deadLetter != nil && q.onDeadLetter != nil {
+		q.onDeadLetter(*deadLetter)
+	}
+	return err
+}
+
+// record records the outcome of executing t, given by the handler error. It returns the task if it was moved to the
+// dead-letter file, so that OnDeadLetter can be called without holding the lock.
+func (q *Queue) record(ctx context.Context, t Task, err error) (*Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.notify()
+	delete(q.running, t.ID)
+
+	if err == nil {
+		delete(q.tasks, t.ID)
+		delete(q.policies, t.ID)
+		return nil, q.saveTasks()
+	}
+	if ctx.Err() != nil {
+		return nil, nil
+	}
+
+	task := q.tasks[t.ID]
+	task.Attempts++
+	task.LastError = err.Error()
+	d := backoff.Stop
+	if !backoff.IsPermanent(err) && !errors.Is(err, ErrNoHandler) {
+		d = q.policyOf(task).NextBackOff()
+	}
+	if d != backoff.Stop {
+		task.Due = q.clock.Now().Add(d)
+		return nil, q.saveTasks()
+	}
+	if err := q.deadLetter(task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// deadLetter moves t to the dead-letter file. The dead letter is written first, so that a crash in between leaves the
+// task in both files rather than losing it.
+func (q *Queue) deadLetter(t *Task) error {
+	deadLetters := append(q.deadLetters, *t)
+	if err := writeTasks(q.path(deadLettersFile), deadLetters); err != nil {
+		return err
+	}
+	q.deadLetters = deadLetters
+	delete(q.tasks, t.ID)
+	delete(q.policies, t.ID)
+	return q.saveTasks()
+}
+
+// policyOf returns the policy instance of t, which uses the clock of the queue. After a restart, the instance is
+// restored by replaying the failed attempts before the last one.
+func (q *Queue) policyOf(t *Task) backoff.Policy {
+	p, ok := q.policies[t.ID]
+	if !ok {
+		p = backoff.NewWithClock(q.policy, q.clock)
+		for i := 1; i < t.Attempts; i++ {
+			p.NextBackOff()
+		}
+		q.policies[t.ID] = p
+	}
+	return p
+}
+
+func (q *Queue) saveTasks() error {
+	return writeTasks(q.path(tasksFile), q.sortedTasks())
+}
+
+func (q *Queue) sortedTasks() []Task {
+	tasks := make([]Task, 0, len(q.tasks))
+	for _, t := range q.tasks {
+		tasks = append(tasks, *t)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].Due.Equal(tasks[j].Due) {
+			return tasks[i].Created.Before(tasks[j].Created)
+		}
+		return tasks[i].Due.Before(tasks[j].Due)
+	})
+	return tasks
+}
+
+// notify wakes up Run to look for due tasks.
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) path(name string) string {
+	return filepath.Join(q.dir, name)
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,316 @@
+package retryqueue_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+	"github.com/ireward/wago/backoff/backofftest"
+	"github.com/ireward/wago/backoff/retryqueue"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	errTest = errors.New("test")
+	start   = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+const waitFor = 5 * time.Second
+
+// run runs q in the background and returns a function stopping it and returning the error of Run.
+func run(q *retryqueue.Queue) func() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- q.Run(ctx)
+	}()
+	return func() error {
+		cancel()
+		return <-done
+	}
+}
+
+func isEmpty(q *retryqueue.Queue) func() bool {
+	return func() bool {
+		return len(q.Pending()) == 0
+	}
+}
+
+func TestQueue(t *testing.T) {
+	q, err := retryqueue.Open(t.TempDir(), backoff.ZeroBackOff(), retryqueue.WithClock(backofftest.NewRecorder(start)))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var (
+		mu      sync.Mutex
+		handled []string
+	)
+	q.Handle("test", func(ctx context.Context, payload []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, string(payload))
+		return nil
+	})
+	for _, payload := range []string{"a", "b", "c"} {
+		_, err := q.Enqueue("test", []byte(payload))
+		assert.NoError(t, err)
+	}
+
+	stop := run(q)
+	assert.Eventually(t, isEmpty(q), waitFor, time.Millisecond)
+	assert.Equal(t, context.Canceled, stop())
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, handled)
+	assert.Empty(t, q.DeadLetters())
+}
+
+func TestQueueRetry(t *testing.T) {
+	clock := backofftest.NewRecorder(start)
+	q, err := retryqueue.Open(t.TempDir(), backoff.ConstantBackOff(time.Minute), retryqueue.WithClock(clock))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var count int32
+	q.Handle("test", func(context.Context, []byte) error {
+		if atomic.AddInt32(&count, 1) < 3 {
+			return errTest
+		}
+		return nil
+	})
+	_, err = q.Enqueue("test", nil)
+	assert.NoError(t, err)
+
+	stop := run(q)
+	assert.Eventually(t, isEmpty(q), waitFor, time.Millisecond)
+	assert.Equal(t, context.Canceled, stop())
+	assert.EqualValues(t, 3, atomic.LoadInt32(&count))
+	assert.Equal(t, []time.Duration{time.Minute, time.Minute}, clock.Delays())
+}
+
+func TestQueueDeadLetter(t *testing.T) {
+	dir := t.TempDir()
+	p := backoff.ZeroBackOff().With(backoff.MaxRetries(2))
+	clock := backofftest.NewRecorder(start)
+
+	var (
+		q           *retryqueue.Queue
+		deadLetters []retryqueue.Task
+	)
+	// The callback may use the queue.
+	q, err := retryqueue.Open(dir, p, retryqueue.WithClock(clock), retryqueue.OnDeadLetter(func(task retryqueue.Task) {
+		assert.Contains(t, q.DeadLetters(), task)
+		deadLetters = append(deadLetters, task)
+	}))
+	if !assert.NoError(t, err) {
+		return
+	}
+	q.Handle("fail", func(context.Context, []byte) error {
+		return errTest
+	})
+	q.Handle("permanent", func(context.Context, []byte) error {
+		return backoff.Permanent(errTest)
+	})
+	failID, _ := q.Enqueue("fail", []byte("payload"))
+	permanentID, _ := q.Enqueue("permanent", nil)
+	unknownID, _ := q.Enqueue("unknown", nil)
+
+	stop := run(q)
+	assert.Eventually(t, isEmpty(q), waitFor, time.Millisecond)
+	assert.Equal(t, context.Canceled, stop())
+
+	attempts := make(map[string]int)
+	for _, task := range q.DeadLetters() {
+		attempts[task.ID] = task.Attempts
+		if task.ID == failID {
+			assert.Equal(t, "payload", string(task.Payload))
+			assert.Equal(t, "test", task.LastError)
+		}
+		if task.ID == unknownID {
+			assert.Equal(t, retryqueue.ErrNoHandler.Error(), task.LastError)
+		}
+	}
+	assert.Equal(t, map[string]int{failID: 3, permanentID: 1, unknownID: 1}, attempts)
+	assert.Equal(t, q.DeadLetters(), deadLetters)
+
+	reopened, err := retryqueue.Open(dir, p)
+	if assert.NoError(t, err) {
+		assert.Empty(t, reopened.Pending())
+		assert.Equal(t, q.DeadLetters(), reopened.DeadLetters())
+	}
+}
+
+func TestQueuePolicyClock(t *testing.T) {
+	p := backoff.ConstantBackOff(time.Hour).With(backoff.MaxElapsedTime(2 * time.Hour))
+	clock := backofftest.NewRecorder(start)
+	q, err := retryqueue.Open(t.TempDir(), p, retryqueue.WithClock(clock))
+	if !assert.NoError(t, err) {
+		return
+	}
+	q.Handle("fail", func(context.Context, []byte) error {
+		return errTest
+	})
+	_, err = q.Enqueue("fail", nil)
+	assert.NoError(t, err)
+
+	stop := run(q)
+	assert.Eventually(t, isEmpty(q), waitFor, time.Millisecond)
+	assert.Equal(t, context.Canceled, stop())
+	if assert.Len(t, q.DeadLetters(), 1) {
+		assert.Equal(t, 3, q.DeadLetters()[0].Attempts)
+	}
+	assert.Equal(t, []time.Duration{time.Hour, time.Hour}, clock.Delays())
+}
+
+func TestOpenInvalidWorkers(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		_, err := retryqueue.Open(t.TempDir(), backoff.ZeroBackOff(), retryqueue.Workers(n))
+		assert.Equal(t, retryqueue.ErrInvalidWorkers, err)
+	}
+}
+
+func TestQueuePersistence(t *testing.T) {
+	dir := t.TempDir()
+	p := backoff.ExponentialBackOff(time.Second, 2)
+	handler := func(context.Context, []byte) error {
+		return errTest
+	}
+
+	clock := backofftest.NewFakeClock(start)
+	q, err := retryqueue.Open(dir, p, retryqueue.WithClock(clock))
+	if !assert.NoError(t, err) {
+		return
+	}
+	q.Handle("test", handler)
+	id, err := q.Enqueue("test", []byte("payload"))
+	assert.NoError(t, err)
+
+	stop := run(q)
+	assert.Eventually(t, func() bool {
+		return q.Pending()[0].Attempts == 1
+	}, waitFor, time.Millisecond)
+	assert.Equal(t, context.Canceled, stop())
+	assert.Equal(t, start.Add(time.Second), q.Pending()[0].Due)
+
+	// After a restart, the policy continues with the second delay.
+	clock = backofftest.NewFakeClock(start.Add(time.Second))
+	q, err = retryqueue.Open(dir, p, retryqueue.WithClock(clock))
+	if !assert.NoError(t, err) {
+		return
+	}
+	q.Handle("test", handler)
+	pending := q.Pending()
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, id, pending[0].ID)
+		assert.Equal(t, "payload", string(pending[0].Payload))
+		assert.Equal(t, "test", pending[0].LastError)
+	}
+
+	stop = run(q)
+	assert.Eventually(t, func() bool {
+		return q.Pending()[0].Attempts == 2
+	}, waitFor, time.Millisecond)
+	assert.Equal(t, context.Canceled, stop())
+	assert.Equal(t, start.Add(3*time.Second), q.Pending()[0].Due)
+}
+
+func TestQueueWorkers(t *testing.T) {
+	const workers = 2
+
+	q, err := retryqueue.Open(t.TempDir(), backoff.ZeroBackOff(), retryqueue.Workers(workers))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var active, maxActive int32
+	q.Handle("test", func(context.Context, []byte) error {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	for i := 0; i < 6; i++ {
+		_, err := q.Enqueue("test", nil)
+		assert.NoError(t, err)
+	}
+
+	stop := run(q)
+	assert.Eventually(t, isEmpty(q), waitFor, time.Millisecond)
+	assert.Equal(t, context.Canceled, stop())
+	assert.EqualValues(t, workers, atomic.LoadInt32(&maxActive))
+}
+
+func TestQueueInterrupted(t *testing.T) {
+	q, err := retryqueue.Open(t.TempDir(), backoff.ZeroBackOff())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	started := make(chan struct{})
+	q.Handle("test", func(ctx context.Context, _ []byte) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	_, err = q.Enqueue("test", nil)
+	assert.NoError(t, err)
+
+	stop := run(q)
+	<-started
+	assert.Equal(t, context.Canceled, stop())
+
+	pending := q.Pending()
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, 0, pending[0].Attempts)
+	}
+}
+
+func TestQueueInterruptedWaiting(t *testing.T) {
+	q, err := retryqueue.Open(t.TempDir(), backoff.ZeroBackOff())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var count int32
+	started, release := make(chan struct{}), make(chan struct{})
+	q.Handle("test", func(context.Context, []byte) error {
+		if atomic.AddInt32(&count, 1) == 1 {
+			close(started)
+			<-release
+		}
+		return nil
+	})
+	for i := 0; i < 2; i++ {
+		_, err = q.Enqueue("test", nil)
+		assert.NoError(t, err)
+	}
+
+	// The second task is waiting for the only worker when Run is stopped.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- q.Run(ctx)
+	}()
+	<-started
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	assert.Equal(t, context.Canceled, <-done)
+	assert.Len(t, q.Pending(), 1)
+
+	stop := run(q)
+	assert.Eventually(t, isEmpty(q), waitFor, time.Millisecond)
+	assert.Equal(t, context.Canceled, stop())
+	assert.EqualValues(t, 2, atomic.LoadInt32(&count))
+}
@@ -0,0 +1,114 @@
+package backoff
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Schedule returns the delays produced by a new instance of the backoff policy p for at most n retries.
+// The returned slice is shorter than n if the policy stops earlier, and empty if n is not positive. Time passes
+// virtually while computing the delays, starting at the current time, so options such as Timeout and MaxElapsedTime
+// behave as in Retry. Options with state shared between invocations of Retry, such as Budget, are previewed without
+// changing that state.
+func Schedule(p Policy, n int) []time.Duration {
+	if n <= 0 {
+		return []time.Duration{}
+	}
+	clock := &virtualClock{now: time.Now()}
+	p = p.New()
+	setClock(p, clock)
+
+	delays := make([]time.Duration, 0, n)
+	for i := 0; i < n; i++ {
+		duration := p.NextBackOff()
+		if duration == Stop {
+			break
+		}
+		delays = append(delays, duration)
+		clock.now = clock.now.Add(duration)
+	}
+	return delays
+}
+
+// Simulation summarizes the total waiting time of many runs of a backoff policy.
+type Simulation struct {
+	// Runs is the number of simulated runs.
+	Runs int
+
+	// Min is the shortest total waiting time of all runs.
+	Min time.Duration
+
+	// Max is the longest total waiting time of all runs.
+	Max time.Duration
+
+	// Mean is the average total waiting time of all runs.
+	Mean time.Duration
+
+	totals []time.Duration
+}
+
+// Simulate computes the Schedule of the backoff policy p for at most n retries the given number of times and
+// summarizes the total waiting times. It is useful to review policies with random delays, e.g. using Jitter.
+func Simulate(p Policy, n, runs int) *Simulation {
+	if runs <= 0 {
+		return &Simulation{Runs: runs}
+	}
+	s := &Simulation{
+		Runs:   runs,
+		totals: make([]time.Duration, runs),
+	}
+
+	var sum float64
+	for i := range s.totals {
+		for _, d := range Schedule(p, n) {
+			s.totals[i] += d
+		}
+		sum += float64(s.totals[i])
+	}
+	sort.Slice(s.totals, func(i, j int) bool {
+		return s.totals[i] < s.totals[j]
+	})
+	s.Min = s.totals[0]
+	s.Max = s.totals[runs-1]
+	s.Mean = time.Duration(sum / float64(runs))
+	return s
+}
+
+// Percentile returns the total waiting time that is not exceeded by the given percentage of runs, e.g. 99 for the
+// 99th percentile, using the nearest-rank method.
+func (s *Simulation) Percentile(percent float64) time.Duration {
+	if len(s.totals) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(percent / 100 * float64(len(s.totals))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(s.totals) {
+		rank = len(s.totals)
+	}
+	return s.totals[rank-1]
+}
+
+// virtualClock is a Clock whose time is advanced manually.
+type virtualClock struct {
+	now time.Time
+}
+
+func (c *virtualClock) Now() time.Time {
+	return c.now
+}
+
+func (c *virtualClock) NewTimer(d time.Duration) Timer {
+	c.now = c.now.Add(d)
+	t := make(firedTimer, 1)
+	t <- c.now
+	return t
+}
+
+// firedTimer is a Timer that has already fired.
+type firedTimer chan time.Time
+
+func (t firedTimer) C() <-chan time.Time { return t }
+func (t firedTimer) Stop() bool          { return false }
@@ -0,0 +1,79 @@
+package backoff_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedule(t *testing.T) {
+	p := backoff.ExponentialBackOff(100*time.Millisecond, 2).With(
+		backoff.MaxInterval(500*time.Millisecond),
+		backoff.MaxRetries(5),
+	)
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		500 * time.Millisecond,
+		500 * time.Millisecond,
+	}
+	assert.Equal(t, expected, backoff.Schedule(p, 10))
+	assert.Equal(t, expected[:3], backoff.Schedule(p, 3))
+}
+
+func TestScheduleMaxElapsedTime(t *testing.T) {
+	p := backoff.ConstantBackOff(time.Minute).With(backoff.MaxElapsedTime(150 * time.Second))
+	expected := []time.Duration{time.Minute, time.Minute, 30 * time.Second}
+	assert.Equal(t, expected, backoff.Schedule(p, 10))
+}
+
+func TestScheduleBudget(t *testing.T) {
+	budget := backoff.NewRetryBudget(0.1, 2)
+	p := backoff.ConstantBackOff(time.Second).With(backoff.Budget(budget), backoff.Jitter(0.5))
+
+	assert.Len(t, backoff.Schedule(p, 10), 2)
+	assert.Len(t, backoff.Schedule(p, 10), 2)
+	backoff.Simulate(p, 10, 100)
+	assert.Equal(t, 2, budget.Remaining())
+}
+
+func TestScheduleNonPositive(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		assert.Empty(t, backoff.Schedule(backoff.ZeroBackOff(), n))
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	const runs = 1000
+
+	p := backoff.ConstantBackOff(time.Second).With(backoff.MaxRetries(4), backoff.Jitter(0.5))
+
+	s := backoff.Simulate(p, 10, runs)
+	assert.Equal(t, runs, s.Runs)
+	assert.GreaterOrEqual(t, s.Min, 2*time.Second)
+	assert.Less(t, s.Max, 4*time.Second)
+	assert.Less(t, s.Min, s.Mean)
+	assert.Less(t, s.Mean, s.Max)
+	assert.Equal(t, s.Min, s.Percentile(0))
+	assert.Equal(t, s.Max, s.Percentile(100))
+	assert.LessOrEqual(t, s.Percentile(50), s.Percentile(99))
+}
+
+func TestSimulateReproducible(t *testing.T) {
+	p1 := backoff.FullJitterBackOff(time.Second, time.Minute, backoff.NewRandom(1)).With(backoff.MaxRetries(5))
+	p2 := backoff.FullJitterBackOff(time.Second, time.Minute, backoff.NewRandom(1)).With(backoff.MaxRetries(5))
+	assert.Equal(t, backoff.Simulate(p1, 10, 100), backoff.Simulate(p2, 10, 100))
+}
+
+func TestSimulateNonPositive(t *testing.T) {
+	s := backoff.Simulate(backoff.ConstantBackOff(time.Second), -1, 10)
+	assert.Equal(t, time.Duration(0), s.Max)
+	assert.Equal(t, time.Duration(0), s.Percentile(50))
+
+	s = backoff.Simulate(backoff.ConstantBackOff(time.Second), 10, -1)
+	assert.Equal(t, time.Duration(0), s.Percentile(50))
+}
@@ -0,0 +1,80 @@
+package backoff
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Ticker delivers ticks on its channel C at the delays of a backoff policy. The first tick is delivered immediately.
+// Unlike time.Ticker, the next delay starts only after the previous tick has been received, ticks are never dropped.
+// The channel is closed when the policy stops, the context is done or Stop is called.
+type Ticker struct {
+	C <-chan time.Time
+
+	c        chan time.Time
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewTicker returns a new Ticker delivering ticks at the delays of the backoff policy p.
+// The options configure the ticker like an invocation of Retry, e.g. WithClock.
+func NewTicker(p Policy, opts ...RetryOption) *Ticker {
+	return NewTickerContext(context.Background(), p, opts...)
+}
+
+// NewTickerContext works like NewTicker but additionally stops the ticker when the context is done.
+func NewTickerContext(ctx context.Context, p Policy, opts ...RetryOption) *Ticker {
+	c := make(chan time.Time)
+	t := &Ticker{
+		C:    c,
+		c:    c,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	o := newRetryOptions(opts)
+	p = p.New()
+	setClock(p, o.clock)
+	go t.run(ctx, p, o.clock)
+	return t
+}
+
+// Stop turns off the ticker and closes its channel. Stop waits until the goroutine delivering the ticks has
+// terminated, so no tick is delivered after Stop returns. It is safe to call Stop multiple times.
+func (t *Ticker) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.stop)
+	})
+	<-t.done
+}
+
+func (t *Ticker) run(ctx context.Context, p Policy, clock Clock) {
+	defer close(t.done)
+	defer close(t.c)
+	for {
+		select {
+		case t.c <- clock.Now():
+		case <-t.stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+
+		duration := p.NextBackOff()
+		if duration == Stop {
+			return
+		}
+
+		timer := clock.NewTimer(duration)
+		select {
+		case <-timer.C():
+		case <-t.stop:
+			timer.Stop()
+			return
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package backoff_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+	"github.com/ireward/wago/backoff/backofftest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTicker(t *testing.T) {
+	clock := backofftest.NewRecorder(start)
+	p := backoff.ExponentialBackOff(time.Second, 2).With(backoff.MaxRetries(3))
+
+	ticker := backoff.NewTicker(p, backoff.WithClock(clock))
+	defer ticker.Stop()
+
+	var ticks []time.Time
+	for tick := range ticker.C {
+		ticks = append(ticks, tick)
+	}
+	assert.Equal(t, []time.Time{
+		start,
+		start.Add(time.Second),
+		start.Add(3 * time.Second),
+		start.Add(7 * time.Second),
+	}, ticks)
+}
+
+func TestTickerStop(t *testing.T) {
+	clock := backofftest.NewFakeClock(start)
+	ticker := backoff.NewTicker(backoff.ConstantBackOff(time.Second), backoff.WithClock(clock))
+
+	assert.Equal(t, start, <-ticker.C)
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Second), <-ticker.C)
+
+	clock.BlockUntil(1)
+	ticker.Stop()
+	ticker.Stop()
+	_, ok := <-ticker.C
+	assert.False(t, ok)
+}
+
+func TestTickerContext(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	clock := backofftest.NewFakeClock(start)
+	ticker := backoff.NewTickerContext(ctx, backoff.ConstantBackOff(time.Hour), backoff.WithClock(clock))
+	defer ticker.Stop()
+
+	<-ticker.C
+	clock.BlockUntil(1)
+	ctxCancel()
+	_, ok := <-ticker.C
+	assert.False(t, ok)
+}
@@ -0,0 +1,179 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultRetryStatusCodes are the response status codes retried by Transport if no status codes are configured.
+var DefaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// maxDrainBytes limits the number of bytes read from a discarded response body so the connection can be reused.
+const maxDrainBytes = 4 << 10
+
+// Transport is a http.RoundTripper that retries requests according to a backoff policy.
+// Requests are retried if the underlying RoundTripper returns an error or if the response has one of the configured
+// status codes. Only idempotent requests are retried unless RetryAllMethods is set. Request bodies are rewound with
+// http.Request.GetBody, requests with a body that cannot be rewound are not retried.
+// If a response contains a Retry-After header, the next attempt is delayed accordingly, see RetryAfter.
+// If the policy stops while the response status code is still retryable, the last response is returned.
+type Transport struct {
+	// Base is the RoundTripper used to make the requests. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	// Policy is the backoff policy used for retrying requests. If nil, requests are not retried.
+	Policy Policy
+
+	// StatusCodes are the response status codes which are retried. If nil, DefaultRetryStatusCodes is used.
+	StatusCodes []int
+
+	// RetryAllMethods allows to retry requests with methods which are not idempotent, such as POST.
+	RetryAllMethods bool
+
+	// Options are passed to Retry for every request.
+	Options []RetryOption
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+// RoundTrip executes a single HTTP transaction and retries it according to the backoff policy.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Policy == nil || !t.retryable(req) {
+		return t.base().RoundTrip(req)
+	}
+
+	var attempt int32
+	opts := append(t.Options[:len(t.Options):len(t.Options)], retryOptionFunc(func(o *retryOptions) {
+		o.discard = func(v interface{}) {
+			if resp, ok := v.(*http.Response); ok && resp != nil {
+				drain(resp)
+			}
+		}
+	}))
+	resp, err := retry(req.Context(), t.Policy, func(ctx context.Context) (*http.Response, error) {
+		// Every attempt uses its own context, so that an abandoned attempt does not outlive AttemptTimeout.
+		r := req.Clone(ctx)
+		if atomic.AddInt32(&attempt, 1) > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, Permanent(err)
+			}
+			r.Body = body
+		}
+
+		resp, err := t.base().RoundTrip(r)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, Permanent(err)
+			}
+			return nil, err
+		}
+		if !t.retryStatus(resp.StatusCode) {
+			return resp, nil
+		}
+
+		err = &statusError{code: resp.StatusCode}
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), newRetryOptions(t.Options).clock); ok {
+			err = RetryAfter(err, d)
+		}
+		return resp, err
+	}, opts)
+
+	var status *statusError
+	if errors.As(err, &status) && resp != nil {
+		var ctxErr *contextError
+		if errors.As(err, &ctxErr) {
+			drain(resp)
+			return nil, err
+		}
+		return resp, nil
+	}
+	return resp, err
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+func (t *Transport) retryable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	return t.RetryAllMethods || idempotent(req)
+}
+
+func (t *Transport) retryStatus(code int) bool {
+	codes := t.StatusCodes
+	if codes == nil {
+		codes = DefaultRetryStatusCodes
+	}
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// idempotent reports whether the request can be repeated safely as defined in RFC 7231 or by an idempotency key.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	_, ok := req.Header["Idempotency-Key"]
+	if !ok {
+		_, ok = req.Header["X-Idempotency-Key"]
+	}
+	return ok
+}
+
+// parseRetryAfter parses the value of a Retry-After header given either in seconds or as HTTP date.
+func parseRetryAfter(value string, clock Clock) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	d := date.Sub(clock.Now())
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+// drain discards the remaining response body and closes it so that the connection can be reused.
+func drain(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+	_ = resp.Body.Close()
+}
+
+// statusError signals that a response had a retryable status code.
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return "backoff: retryable response status " + strconv.Itoa(e.code)
+}
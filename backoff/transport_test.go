@@ -0,0 +1,275 @@
+package backoff_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+	"github.com/ireward/wago/backoff/backofftest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newFlakyServer returns a server that responds with the given status codes in order and with 200 afterwards.
+// The bodies of all requests are recorded.
+func newFlakyServer(t *testing.T, header http.Header, codes ...int) (*httptest.Server, *[]string) {
+	var (
+		count  int32
+		bodies []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		bodies = append(bodies, string(body))
+
+		i := int(atomic.AddInt32(&count, 1)) - 1
+		if i < len(codes) {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(codes[i])
+			_, _ = w.Write([]byte("failed"))
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &bodies
+}
+
+func newTestTransport(clock backoff.Clock) *backoff.Transport {
+	return &backoff.Transport{
+		Policy:  backoff.ConstantBackOff(time.Second).With(backoff.MaxRetries(3)),
+		Options: []backoff.RetryOption{backoff.WithClock(clock)},
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv, bodies := newFlakyServer(t, nil, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	clock := backofftest.NewRecorder(start)
+	client := &http.Client{Transport: newTestTransport(clock)}
+
+	req, err := http.NewRequest(http.MethodPut, srv.URL, bytes.NewBufferString("body"))
+	assert.NoError(t, err)
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "ok", string(body))
+	assert.Equal(t, []string{"body", "body", "body"}, *bodies)
+	assert.Equal(t, []time.Duration{time.Second, time.Second}, clock.Delays())
+}
+
+func TestTransportNonIdempotent(t *testing.T) {
+	srv, bodies := newFlakyServer(t, nil, http.StatusServiceUnavailable)
+	clock := backofftest.NewRecorder(start)
+	transport := newTestTransport(clock)
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Post(srv.URL, "text/plain", bytes.NewBufferString("body"))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Len(t, *bodies, 1)
+
+	transport.RetryAllMethods = true
+	resp, err = client.Post(srv.URL, "text/plain", bytes.NewBufferString("body"))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, *bodies, 2)
+}
+
+func TestTransportIdempotencyKey(t *testing.T) {
+	srv, bodies := newFlakyServer(t, nil, http.StatusServiceUnavailable)
+	client := &http.Client{Transport: newTestTransport(backofftest.NewRecorder(start))}
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL, bytes.NewBufferString("body"))
+	assert.NoError(t, err)
+	req.Header.Set("Idempotency-Key", "key")
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"body", "body"}, *bodies)
+}
+
+func TestTransportExhausted(t *testing.T) {
+	srv, bodies := newFlakyServer(t, nil, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway,
+		http.StatusBadGateway, http.StatusBadGateway)
+	client := &http.Client{Transport: newTestTransport(backofftest.NewRecorder(start))}
+
+	resp, err := client.Get(srv.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, "failed", string(body))
+	assert.Len(t, *bodies, 4)
+}
+
+func TestTransportStatusCodes(t *testing.T) {
+	srv, bodies := newFlakyServer(t, nil, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	transport := newTestTransport(backofftest.NewRecorder(start))
+	transport.StatusCodes = []int{http.StatusInternalServerError}
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(srv.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Len(t, *bodies, 2)
+}
+
+func TestTransportRetryAfter(t *testing.T) {
+	header := http.Header{"Retry-After": []string{"5"}}
+	srv, _ := newFlakyServer(t, header, http.StatusTooManyRequests)
+	clock := backofftest.NewRecorder(start)
+	client := &http.Client{Transport: newTestTransport(clock)}
+
+	resp, err := client.Get(srv.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []time.Duration{5 * time.Second}, clock.Delays())
+}
+
+func TestTransportRetryAfterDate(t *testing.T) {
+	header := http.Header{"Retry-After": []string{start.Add(time.Minute).Format(http.TimeFormat)}}
+	srv, _ := newFlakyServer(t, header, http.StatusServiceUnavailable)
+	clock := backofftest.NewRecorder(start)
+	client := &http.Client{Transport: newTestTransport(clock)}
+
+	resp, err := client.Get(srv.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []time.Duration{time.Minute}, clock.Delays())
+}
+
+func TestTransportConnectionError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	clock := backofftest.NewRecorder(start)
+	client := &http.Client{Transport: newTestTransport(clock)}
+
+	_, err := client.Get(srv.URL)
+	assert.Error(t, err)
+	assert.Len(t, clock.Delays(), 3)
+}
+
+func TestTransportCancel(t *testing.T) {
+	srv, bodies := newFlakyServer(t, nil, http.StatusServiceUnavailable)
+	clock := backofftest.NewFakeClock(start)
+	client := &http.Client{Transport: newTestTransport(clock)}
+
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	go func() {
+		clock.BlockUntil(1)
+		ctxCancel()
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	assert.NoError(t, err)
+	_, err = client.Do(req)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, *bodies, 1)
+}
+
+func TestTransportAttemptTimeout(t *testing.T) {
+	srv, _ := newFlakyServer(t, nil, http.StatusServiceUnavailable)
+	transport := &backoff.Transport{
+		Policy: backoff.ZeroBackOff().With(backoff.MaxRetries(3)),
+		Options: []backoff.RetryOption{
+			backoff.AttemptTimeout(time.Second),
+			// The deadline of the fake clock is passed to the real connection, so it must not be in the past.
+			backoff.WithClock(backofftest.NewFakeClock(time.Now())),
+		},
+	}
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(srv.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", string(body))
+}
+
+// roundTripperFunc wraps a func so it satisfies the http.RoundTripper interface.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// closeRecorder is a response body recording whether it has been closed.
+type closeRecorder struct {
+	io.Reader
+	closed int32
+}
+
+func (b *closeRecorder) Close() error {
+	atomic.StoreInt32(&b.closed, 1)
+	return nil
+}
+
+func TestTransportAttemptTimeoutAbandoned(t *testing.T) {
+	var (
+		count     int32
+		started   = make(chan struct{})
+		release   = make(chan struct{})
+		abandoned = &closeRecorder{Reader: bytes.NewBufferString("late")}
+	)
+	clock := backofftest.NewFakeClock(start)
+	transport := &backoff.Transport{
+		Policy:  backoff.ZeroBackOff().With(backoff.MaxRetries(3)),
+		Options: []backoff.RetryOption{backoff.AttemptTimeout(time.Second), backoff.WithClock(clock)},
+	}
+	go func() {
+		<-started
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}()
+	transport.Base = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		_, ok := req.Context().Deadline()
+		assert.True(t, ok)
+		if atomic.AddInt32(&count, 1) == 1 {
+			// The first attempt ignores its context and responds after it has been abandoned.
+			close(started)
+			<-release
+			return &http.Response{StatusCode: http.StatusOK, Body: abandoned}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBufferString("ok"))}, nil
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	assert.NoError(t, err)
+	resp, err := transport.RoundTrip(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", string(body))
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&abandoned.closed) == 1
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,228 @@
+// Package circuitbreaker implements the circuit breaker pattern. A circuit breaker stops calls to a failing dependency
+// for a while after too many failures, and probes the dependency before allowing calls again.
+// The durations the circuit breaker stays open are determined by a backoff policy.
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+)
+
+var (
+	// ErrOpen is returned by Execute if the circuit breaker is open. The error is wrapped with backoff.RetryAfter,
+	// so that backoff.Retry waits until the next probe is allowed.
+	ErrOpen = errors.New("circuitbreaker: circuit breaker is open")
+
+	// ErrTooManyCalls is returned by Execute if the circuit breaker is half-open and the maximum number of probes is
+	// already running.
+	ErrTooManyCalls = errors.New("circuitbreaker: too many calls in half-open state")
+)
+
+// State is the state of a CircuitBreaker.
+type State int
+
+const (
+	// Closed is the state in which all calls are executed and failures are counted.
+	Closed State = iota
+	// Open is the state in which no calls are executed.
+	Open
+	// HalfOpen is the state in which a limited number of calls is executed to probe the dependency.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreaker is a circuit breaker. It is safe for concurrent use.
+type CircuitBreaker struct {
+	failureThreshold int
+	failureRatio     float64
+	minCalls         int
+	window           *window
+	policy           backoff.Policy
+	halfOpenCalls    int
+	isFailure        func(err error) bool
+	onStateChange    []func(from, to State)
+	clock            backoff.Clock
+
+	mu                sync.Mutex
+	state             State
+	generation        uint64
+	openPolicy        backoff.Policy
+	openDelay         time.Duration
+	openUntil         time.Time
+	halfOpenActive    int
+	halfOpenSuccesses int
+	changes           []stateChange
+}
+
+type stateChange struct {
+	from State
+	to   State
+}
+
+// New creates a closed CircuitBreaker. Without options, it opens after 5 failures within 10 seconds and stays open
+// for an exponentially growing duration starting at 1 second up to 1 minute.
+func New(opts ...Option) *CircuitBreaker {
+	cb := &CircuitBreaker{
+		failureThreshold: 5,
+		window:           newWindow(10 * time.Second),
+		policy:           backoff.ExponentialBackOff(time.Second, 2).With(backoff.MaxInterval(time.Minute)),
+		halfOpenCalls:    1,
+		isFailure: func(err error) bool {
+			return !errors.Is(err, context.Canceled)
+		},
+		clock: backoff.SystemClock(),
+	}
+	for _, opt := range opts {
+		opt.apply(cb)
+	}
+	cb.openPolicy = cb.policy.New()
+	return cb
+}
+
+// State returns the current state of the circuit breaker.
+func (cb *CircuitBreaker) State() State {
+	cb.mu.Lock()
+	defer cb.unlock()
+	cb.refresh(cb.clock.Now())
+	return cb.state
+}
+
+// Execute calls f if the circuit breaker allows it and records the outcome. Otherwise, ErrOpen or ErrTooManyCalls
+// is returned. Execute can be called from the function given to backoff.Retry to combine retries with the circuit
+// breaker.
+func (cb *CircuitBreaker) Execute(ctx context.Context, f func(ctx context.Context) error) (err error) {
+	generation, err := cb.before()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			cb.after(generation, true, false)
+			panic(r)
+		}
+	}()
+	err = f(ctx)
+	cb.after(generation, err != nil && cb.isFailure(err), err == nil)
+	return err
+}
+
+func (cb *CircuitBreaker) before() (uint64, error) {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	now := cb.clock.Now()
+	cb.refresh(now)
+	switch cb.state {
+	case Open:
+		return 0, backoff.RetryAfter(ErrOpen, cb.openUntil.Sub(now))
+	case HalfOpen:
+		if cb.halfOpenActive >= cb.halfOpenCalls {
+			return 0, ErrTooManyCalls
+		}
+		cb.halfOpenActive++
+	}
+	return cb.generation, nil
+}
+
+func (cb *CircuitBreaker) after(generation uint64, failure, success bool) {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	now := cb.clock.Now()
+	cb.refresh(now)
+	if generation != cb.generation {
+		return
+	}
+	switch cb.state {
+	case Closed:
+		if !failure && !success {
+			return
+		}
+		cb.window.record(now, failure)
+		if failure && cb.trip(now) {
+			cb.open(now)
+		}
+	case HalfOpen:
+		cb.halfOpenActive--
+		if failure {
+			cb.open(now)
+			return
+		}
+		if success {
+			cb.halfOpenSuccesses++
+			if cb.halfOpenSuccesses >= cb.halfOpenCalls {
+				cb.close()
+			}
+		}
+	}
+}
+
+// trip reports whether the failures within the window exceed the configured thresholds.
+func (cb *CircuitBreaker) trip(now time.Time) bool {
+	successes, failures := cb.window.counts(now)
+	if failures < cb.failureThreshold {
+		return false
+	}
+	if cb.failureRatio <= 0 {
+		return true
+	}
+	total := successes + failures
+	return total >= cb.minCalls && float64(failures)/float64(total) >= cb.failureRatio
+}
+
+// refresh moves an open circuit breaker to the half-open state once the open duration has passed.
+func (cb *CircuitBreaker) refresh(now time.Time) {
+	if cb.state == Open && !now.Before(cb.openUntil) {
+		cb.halfOpenActive = 0
+		cb.halfOpenSuccesses = 0
+		cb.setState(HalfOpen)
+	}
+}
+
+func (cb *CircuitBreaker) open(now time.Time) {
+	if d := cb.openPolicy.NextBackOff(); d != backoff.Stop {
+		cb.openDelay = d
+	}
+	cb.openUntil = now.Add(cb.openDelay)
+	cb.setState(Open)
+}
+
+func (cb *CircuitBreaker) close() {
+	cb.window.reset()
+	cb.openPolicy = cb.policy.New()
+	cb.setState(Closed)
+}
+
+func (cb *CircuitBreaker) setState(state State) {
+	cb.changes = append(cb.changes, stateChange{from: cb.state, to: state})
+	cb.state = state
+	cb.generation++
+}
+
+// unlock unlocks the mutex and afterwards notifies about the state changes made while holding it.
+func (cb *CircuitBreaker) unlock() {
+	changes := cb.changes
+	cb.changes = nil
+	cb.mu.Unlock()
+
+	for _, c := range changes {
+		for _, f := range cb.onStateChange {
+			f(c.from, c.to)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+	"github.com/ireward/wago/backoff/backofftest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	errTest = errors.New("test")
+	start   = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func fail(context.Context) error    { return errTest }
+func succeed(context.Context) error { return nil }
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := backofftest.NewFakeClock(start)
+
+	var changes []State
+	cb := New(
+		FailureThreshold(3),
+		OpenPolicy(backoff.ExponentialBackOff(time.Second, 2)),
+		WithClock(clock),
+		OnStateChange(func(from, to State) {
+			changes = append(changes, to)
+		}),
+	)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, Closed, cb.State())
+		assert.ErrorIs(t, cb.Execute(ctx, fail), errTest)
+	}
+	assert.Equal(t, Open, cb.State())
+	assert.ErrorIs(t, cb.Execute(ctx, succeed), ErrOpen)
+
+	clock.Advance(time.Second)
+	assert.Equal(t, HalfOpen, cb.State())
+	assert.ErrorIs(t, cb.Execute(ctx, fail), errTest)
+	assert.Equal(t, Open, cb.State())
+
+	clock.Advance(time.Second)
+	assert.Equal(t, Open, cb.State())
+	clock.Advance(time.Second)
+	assert.Equal(t, HalfOpen, cb.State())
+	assert.NoError(t, cb.Execute(ctx, succeed))
+	assert.Equal(t, Closed, cb.State())
+
+	assert.Equal(t, []State{Open, HalfOpen, Open, HalfOpen, Closed}, changes)
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	clock := backofftest.NewFakeClock(start)
+	cb := New(FailureThreshold(2), Window(10*time.Second), WithClock(clock))
+	ctx := context.Background()
+
+	assert.ErrorIs(t, cb.Execute(ctx, fail), errTest)
+	clock.Advance(11 * time.Second)
+	assert.ErrorIs(t, cb.Execute(ctx, fail), errTest)
+	assert.Equal(t, Closed, cb.State())
+	clock.Advance(5 * time.Second)
+	assert.ErrorIs(t, cb.Execute(ctx, fail), errTest)
+	assert.Equal(t, Open, cb.State())
+}
+
+func TestCircuitBreakerFailureRatio(t *testing.T) {
+	cb := New(FailureThreshold(2), FailureRatio(0.5, 4))
+	ctx := context.Background()
+
+	assert.ErrorIs(t, cb.Execute(ctx, fail), errTest)
+	assert.ErrorIs(t, cb.Execute(ctx, fail), errTest)
+	assert.Equal(t, Closed, cb.State())
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, cb.Execute(ctx, succeed))
+	}
+	assert.ErrorIs(t, cb.Execute(ctx, fail), errTest)
+	assert.Equal(t, Closed, cb.State())
+	assert.ErrorIs(t, cb.Execute(ctx, fail), errTest)
+	assert.Equal(t, Open, cb.State())
+}
+
+func TestCircuitBreakerIsFailure(t *testing.T) {
+	cb := New(FailureThreshold(1), IsFailure(func(err error) bool {
+		return !errors.Is(err, errTest)
+	}))
+
+	assert.ErrorIs(t, cb.Execute(context.Background(), fail), errTest)
+	assert.Equal(t, Closed, cb.State())
+}
+
+func TestCircuitBreakerHalfOpenCalls(t *testing.T) {
+	clock := backofftest.NewFakeClock(start)
+	cb := New(FailureThreshold(1), HalfOpenCalls(2), OpenPolicy(backoff.ConstantBackOff(time.Second)),
+		WithClock(clock))
+	ctx := context.Background()
+
+	assert.ErrorIs(t, cb.Execute(ctx, fail), errTest)
+	clock.Advance(time.Second)
+
+	err := cb.Execute(ctx, func(ctx context.Context) error {
+		return cb.Execute(ctx, func(ctx context.Context) error {
+			assert.ErrorIs(t, cb.Execute(ctx, succeed), ErrTooManyCalls)
+			return nil
+		})
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Closed, cb.State())
+}
+
+func TestCircuitBreakerRetry(t *testing.T) {
+	clock := backofftest.NewRecorder(start)
+	cb := New(FailureThreshold(2), OpenPolicy(backoff.ConstantBackOff(10*time.Second)), WithClock(clock))
+
+	var count int
+	err := backoff.RetryContext(context.Background(), backoff.ConstantBackOff(time.Second), func(ctx context.Context) error {
+		return cb.Execute(ctx, func(context.Context) error {
+			count++
+			if count <= 2 {
+				return errTest
+			}
+			return nil
+		})
+	}, backoff.WithClock(clock))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, []time.Duration{time.Second, time.Second, 9 * time.Second}, clock.Delays())
+}
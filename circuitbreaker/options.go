@@ -0,0 +1,83 @@
+package circuitbreaker
+
+import (
+	"time"
+
+	"github.com/ireward/wago/backoff"
+)
+
+// An Option configures a CircuitBreaker.
+type Option interface {
+	apply(cb *CircuitBreaker)
+}
+
+// optionFunc wraps a func so it satisfies the Option interface.
+type optionFunc func(*CircuitBreaker)
+
+func (f optionFunc) apply(cb *CircuitBreaker) {
+	f(cb)
+}
+
+// FailureThreshold configures the number of failures within the sliding window which opens the circuit breaker.
+func FailureThreshold(failures int) Option {
+	return optionFunc(func(cb *CircuitBreaker) {
+		cb.failureThreshold = failures
+	})
+}
+
+// FailureRatio configures the circuit breaker to open only if additionally the ratio of failed to all calls within
+// the sliding window reaches the given ratio and at least minCalls have been made.
+func FailureRatio(ratio float64, minCalls int) Option {
+	return optionFunc(func(cb *CircuitBreaker) {
+		cb.failureRatio = ratio
+		cb.minCalls = minCalls
+	})
+}
+
+// Window configures the duration of the sliding window in which failures are counted.
+func Window(d time.Duration) Option {
+	return optionFunc(func(cb *CircuitBreaker) {
+		cb.window = newWindow(d)
+	})
+}
+
+// OpenPolicy configures the backoff policy which determines how long the circuit breaker stays open before a
+// half-open probe is allowed. The policy is advanced every time a probe fails and reset when the circuit breaker
+// closes. If the policy stops, the last duration is reused.
+func OpenPolicy(p backoff.Policy) Option {
+	return optionFunc(func(cb *CircuitBreaker) {
+		cb.policy = p
+	})
+}
+
+// HalfOpenCalls configures the number of concurrent probes allowed in the half-open state. The circuit breaker
+// closes once all of them succeeded.
+func HalfOpenCalls(calls int) Option {
+	return optionFunc(func(cb *CircuitBreaker) {
+		cb.halfOpenCalls = calls
+	})
+}
+
+// IsFailure configures which errors returned by the executed functions are counted as failures.
+// Other errors are counted neither as failures nor as successes. By default, all errors except context.Canceled are
+// failures.
+func IsFailure(isFailure func(err error) bool) Option {
+	return optionFunc(func(cb *CircuitBreaker) {
+		cb.isFailure = isFailure
+	})
+}
+
+// OnStateChange configures a function that is called whenever the state of the circuit breaker changes.
+// OnStateChange can be given multiple times, the functions are called in the given order.
+func OnStateChange(f func(from, to State)) Option {
+	return optionFunc(func(cb *CircuitBreaker) {
+		cb.onStateChange = append(cb.onStateChange, f)
+	})
+}
+
+// WithClock configures the circuit breaker to use the given Clock.
+func WithClock(c backoff.Clock) Option {
+	return optionFunc(func(cb *CircuitBreaker) {
+		cb.clock = c
+	})
+}
@@ -0,0 +1,63 @@
+package circuitbreaker
+
+import (
+	"time"
+)
+
+// numBuckets is the number of buckets the sliding window is divided into.
+const numBuckets = 10
+
+// window counts successes and failures within a sliding time window.
+type window struct {
+	size    time.Duration
+	width   time.Duration
+	buckets [numBuckets]bucket
+}
+
+type bucket struct {
+	start     time.Time
+	successes int
+	failures  int
+}
+
+func newWindow(size time.Duration) *window {
+	width := size / numBuckets
+	if width <= 0 {
+		width = 1
+	}
+	return &window{
+		size:  size,
+		width: width,
+	}
+}
+
+// record adds a success or failure at the given time.
+func (w *window) record(now time.Time, failure bool) {
+	start := now.Truncate(w.width)
+	b := &w.buckets[(start.UnixNano()/int64(w.width))%numBuckets]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start}
+	}
+	if failure {
+		b.failures++
+	} else {
+		b.successes++
+	}
+}
+
+// counts returns the number of successes and failures within the window ending at the given time.
+func (w *window) counts(now time.Time) (successes, failures int) {
+	from := now.Add(-w.size)
+	for _, b := range w.buckets {
+		if b.start.Add(w.width).After(from) && !b.start.After(now) {
+			successes += b.successes
+			failures += b.failures
+		}
+	}
+	return
+}
+
+// reset removes all successes and failures.
+func (w *window) reset() {
+	w.buckets = [numBuckets]bucket{}
+}
@@ -0,0 +1,36 @@
+package ratelimit
+
+import (
+	"github.com/ireward/wago/backoff"
+)
+
+// An Option configures a TokenBucket or SlidingWindow.
+type Option interface {
+	apply(o *options)
+}
+
+// optionFunc wraps a func so it satisfies the Option interface.
+type optionFunc func(*options)
+
+func (f optionFunc) apply(o *options) {
+	f(o)
+}
+
+type options struct {
+	clock backoff.Clock
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{clock: backoff.SystemClock()}
+	for _, opt := range opts {
+		opt.apply(o)
+	}
+	return o
+}
+
+// WithClock configures the limiter to use the given Clock.
+func WithClock(c backoff.Clock) Option {
+	return optionFunc(func(o *options) {
+		o.clock = c
+	})
+}
@@ -0,0 +1,99 @@
+// Package ratelimit implements client-side rate limiters. The limiters can be used with backoff.RateLimit to limit
+// the rate of retried attempts.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+)
+
+// ErrDeadline is returned by Wait if the context would be done before the operation is allowed.
+var ErrDeadline = errors.New("ratelimit: wait would exceed context deadline")
+
+// Limiter limits the rate of operations. It is implemented by TokenBucket and SlidingWindow.
+type Limiter interface {
+	// Allow reports whether an operation may happen now and consumes the permission if so.
+	Allow() bool
+
+	// Reserve reserves the permission for an operation and returns a Reservation telling how long to wait.
+	Reserve() *Reservation
+
+	// Wait blocks until an operation is allowed or the context is done.
+	Wait(ctx context.Context) error
+}
+
+var (
+	_ Limiter = (*TokenBucket)(nil)
+	_ Limiter = (*SlidingWindow)(nil)
+
+	_ backoff.Limiter = (*TokenBucket)(nil)
+	_ backoff.Limiter = (*SlidingWindow)(nil)
+)
+
+// Reservation holds the permission for an operation at a future time.
+type Reservation struct {
+	clock     backoff.Clock
+	timeToAct time.Time
+	never     bool
+	cancel    func()
+}
+
+// InfDuration is the delay of a Reservation of a limiter that does not allow any operations.
+const InfDuration = time.Duration(math.MaxInt64)
+
+// never returns a Reservation for a limiter that does not allow any operations.
+func never(clock backoff.Clock) *Reservation {
+	return &Reservation{clock: clock, never: true}
+}
+
+// Delay returns the duration to wait before the reserved operation may happen, or InfDuration if it never may.
+func (r *Reservation) Delay() time.Duration {
+	if r.never {
+		return InfDuration
+	}
+	d := r.timeToAct.Sub(r.clock.Now())
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// Cancel returns the reserved permission to the limiter if possible, so that other operations can use it.
+func (r *Reservation) Cancel() {
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+}
+
+// wait blocks until the reservation can be used or the context is done, in which case the reservation is cancelled.
+func wait(ctx context.Context, r *Reservation) error {
+	if r.never {
+		if _, ok := ctx.Deadline(); ok {
+			return ErrDeadline
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	d := r.Delay()
+	if d == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && r.timeToAct.After(deadline) {
+		r.Cancel()
+		return ErrDeadline
+	}
+	timer := r.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
@@ -0,0 +1,189 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+	"github.com/ireward/wago/backoff/backofftest"
+	"github.com/ireward/wago/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	errTest = errors.New("test")
+	start   = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func TestTokenBucketAllow(t *testing.T) {
+	clock := backofftest.NewFakeClock(start)
+	b := ratelimit.NewTokenBucket(2, 3, ratelimit.WithClock(clock))
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, b.Allow())
+	}
+	assert.False(t, b.Allow())
+
+	clock.Advance(500 * time.Millisecond)
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow())
+
+	clock.Advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		assert.True(t, b.Allow())
+	}
+	assert.False(t, b.Allow())
+}
+
+func TestTokenBucketReserve(t *testing.T) {
+	clock := backofftest.NewFakeClock(start)
+	b := ratelimit.NewTokenBucket(2, 1, ratelimit.WithClock(clock))
+
+	assert.Equal(t, time.Duration(0), b.Reserve().Delay())
+	assert.Equal(t, 500*time.Millisecond, b.Reserve().Delay())
+	r := b.Reserve()
+	assert.Equal(t, time.Second, r.Delay())
+
+	r.Cancel()
+	assert.Equal(t, time.Second, b.Reserve().Delay())
+
+	clock.Advance(time.Second)
+	assert.Equal(t, 500*time.Millisecond, b.Reserve().Delay())
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	clock := backofftest.NewFakeClock(start)
+	b := ratelimit.NewTokenBucket(1, 1, ratelimit.WithClock(clock))
+
+	assert.NoError(t, b.Wait(context.Background()))
+
+	go func() {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}()
+	assert.NoError(t, b.Wait(context.Background()))
+	assert.Equal(t, start.Add(time.Second), clock.Now())
+}
+
+func TestTokenBucketWaitCancel(t *testing.T) {
+	clock := backofftest.NewFakeClock(start)
+	b := ratelimit.NewTokenBucket(1, 1, ratelimit.WithClock(clock))
+	assert.True(t, b.Allow())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		clock.BlockUntil(1)
+		cancel()
+	}()
+	assert.ErrorIs(t, b.Wait(ctx), context.Canceled)
+
+	// The cancelled reservation returned its token.
+	assert.Equal(t, time.Second, b.Reserve().Delay())
+}
+
+func TestTokenBucketWaitDeadline(t *testing.T) {
+	b := ratelimit.NewTokenBucket(1, 1)
+	assert.True(t, b.Allow())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, b.Wait(ctx), ratelimit.ErrDeadline)
+}
+
+func assertNeverAllowed(t *testing.T, l ratelimit.Limiter) {
+	assert.False(t, l.Allow())
+	assert.Equal(t, ratelimit.InfDuration, l.Reserve().Delay())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	assert.ErrorIs(t, l.Wait(ctx), ratelimit.ErrDeadline)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go cancel()
+	assert.ErrorIs(t, l.Wait(ctx), context.Canceled)
+}
+
+func TestTokenBucketInvalid(t *testing.T) {
+	for _, rate := range []float64{0, -1} {
+		clock := backofftest.NewFakeClock(start)
+		b := ratelimit.NewTokenBucket(rate, 1, ratelimit.WithClock(clock))
+		assert.True(t, b.Allow())
+		clock.Advance(time.Hour)
+		assertNeverAllowed(t, b)
+	}
+	for _, burst := range []int{0, -1} {
+		assertNeverAllowed(t, ratelimit.NewTokenBucket(1, burst))
+	}
+}
+
+func TestSlidingWindowAllow(t *testing.T) {
+	clock := backofftest.NewFakeClock(start)
+	w := ratelimit.NewSlidingWindow(2, time.Second, ratelimit.WithClock(clock))
+
+	assert.True(t, w.Allow())
+	clock.Advance(600 * time.Millisecond)
+	assert.True(t, w.Allow())
+	assert.False(t, w.Allow())
+
+	clock.Advance(400 * time.Millisecond)
+	assert.True(t, w.Allow())
+	assert.False(t, w.Allow())
+
+	clock.Advance(600 * time.Millisecond)
+	assert.True(t, w.Allow())
+}
+
+func TestSlidingWindowReserve(t *testing.T) {
+	clock := backofftest.NewFakeClock(start)
+	w := ratelimit.NewSlidingWindow(2, time.Second, ratelimit.WithClock(clock))
+
+	assert.Equal(t, time.Duration(0), w.Reserve().Delay())
+	clock.Advance(200 * time.Millisecond)
+	assert.Equal(t, time.Duration(0), w.Reserve().Delay())
+	r := w.Reserve()
+	assert.Equal(t, 800*time.Millisecond, r.Delay())
+
+	r.Cancel()
+	assert.Equal(t, 800*time.Millisecond, w.Reserve().Delay())
+	assert.Equal(t, time.Second, w.Reserve().Delay())
+}
+
+func TestSlidingWindowWait(t *testing.T) {
+	clock := backofftest.NewFakeClock(start)
+	w := ratelimit.NewSlidingWindow(1, time.Second, ratelimit.WithClock(clock))
+
+	assert.NoError(t, w.Wait(context.Background()))
+
+	go func() {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}()
+	assert.NoError(t, w.Wait(context.Background()))
+	assert.False(t, w.Allow())
+}
+
+func TestSlidingWindowInvalid(t *testing.T) {
+	for _, limit := range []int{0, -1} {
+		assertNeverAllowed(t, ratelimit.NewSlidingWindow(limit, time.Second))
+	}
+}
+
+func TestRetryRateLimit(t *testing.T) {
+	clock := backofftest.NewFakeClock(start)
+	b := ratelimit.NewTokenBucket(1, 5, ratelimit.WithClock(clock))
+
+	var count int
+	err := backoff.Retry(backoff.ZeroBackOff(), func() error {
+		count++
+		if count < 5 {
+			return errTest
+		}
+		return nil
+	}, backoff.RateLimit(b))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, count)
+	assert.False(t, b.Allow())
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+)
+
+// SlidingWindow is a rate limiter that allows at most limit operations within any time window of the given size.
+// It is safe for concurrent use.
+type SlidingWindow struct {
+	clock  backoff.Clock
+	window time.Duration
+
+	mu    sync.Mutex
+	times []time.Time
+	next  int
+}
+
+// NewSlidingWindow creates a SlidingWindow allowing at most limit operations within every window. If limit is not
+// positive, no operations are allowed and Wait blocks until the context is done.
+func NewSlidingWindow(limit int, window time.Duration, opts ...Option) *SlidingWindow {
+	o := newOptions(opts)
+	if limit < 0 {
+		limit = 0
+	}
+	return &SlidingWindow{
+		clock:  o.clock,
+		window: window,
+		times:  make([]time.Time, limit),
+	}
+}
+
+// Allow reports whether an operation may happen now and records it if so.
+func (w *SlidingWindow) Allow() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.times) == 0 {
+		return false
+	}
+	now := w.clock.Now()
+	if w.times[w.next].Add(w.window).After(now) {
+		return false
+	}
+	w.record(now)
+	return true
+}
+
+// Reserve records an operation at the earliest time allowed by the window and returns a Reservation telling how
+// long to wait until then. Only the latest reservation can be cancelled.
+func (w *SlidingWindow) Reserve() *Reservation {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.times) == 0 {
+		return never(w.clock)
+	}
+	timeToAct := w.clock.Now()
+	if earliest := w.times[w.next].Add(w.window); earliest.After(timeToAct) {
+		timeToAct = earliest
+	}
+	slot, previous := w.next, w.times[w.next]
+	w.record(timeToAct)
+	return &Reservation{
+		clock:     w.clock,
+		timeToAct: timeToAct,
+		cancel: func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			// Only the latest reservation can be returned without reordering the recorded times.
+			latest := (w.next + len(w.times) - 1) % len(w.times)
+			if slot == latest && w.times[slot].Equal(timeToAct) && w.clock.Now().Before(timeToAct) {
+				w.times[slot] = previous
+				w.next = slot
+			}
+		},
+	}
+}
+
+// Wait blocks until an operation is allowed or the context is done. If the context would be done before, ErrDeadline
+// is returned immediately.
+func (w *SlidingWindow) Wait(ctx context.Context) error {
+	return wait(ctx, w.Reserve())
+}
+
+// record stores the time of an operation replacing the oldest one.
+func (w *SlidingWindow) record(t time.Time) {
+	w.times[w.next] = t
+	w.next = (w.next + 1) % len(w.times)
+}
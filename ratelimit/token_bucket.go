@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+)
+
+// TokenBucket is a token bucket rate limiter. The bucket holds at most burst tokens and is refilled with rate tokens
+// per second. Every operation consumes a token. It is safe for concurrent use.
+type TokenBucket struct {
+	clock backoff.Clock
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a full TokenBucket allowing rate operations per second with bursts of at most burst
+// operations. If rate is not positive, the bucket is never refilled, so no more operations are allowed after the
+// first burst. If burst is not positive, no operations are allowed at all. Wait then blocks until the context is done.
+func NewTokenBucket(rate float64, burst int, opts ...Option) *TokenBucket {
+	o := newOptions(opts)
+	return &TokenBucket{
+		clock:  o.clock,
+		rate:   rate,
+		burst:  math.Max(float64(burst), 0),
+		tokens: math.Max(float64(burst), 0),
+		last:   o.clock.Now(),
+	}
+}
+
+// Allow reports whether an operation may happen now and consumes a token if so.
+func (b *TokenBucket) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(b.clock.Now())
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Reserve consumes a token, which may be refilled only in the future, and returns a Reservation telling how long to
+// wait until the operation may happen.
+func (b *TokenBucket) Reserve() *Reservation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	b.refill(now)
+	if b.burst <= 0 || (b.rate <= 0 && b.tokens < 1) {
+		return never(b.clock)
+	}
+	b.tokens--
+
+	timeToAct := now
+	if b.tokens < 0 {
+		timeToAct = now.Add(time.Duration(-b.tokens / b.rate * float64(time.Second)))
+	}
+	return &Reservation{
+		clock:     b.clock,
+		timeToAct: timeToAct,
+		cancel: func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if b.clock.Now().Before(timeToAct) {
+				b.refill(b.clock.Now())
+				b.tokens++
+				if b.tokens > b.burst {
+					b.tokens = b.burst
+				}
+			}
+		},
+	}
+}
+
+// Wait blocks until an operation is allowed or the context is done. If the context would be done before, ErrDeadline
+// is returned immediately.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	return wait(ctx, b.Reserve())
+}
+
+// refill adds the tokens accumulated since the last refill.
+func (b *TokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 && b.rate > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+}
@@ -0,0 +1,65 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/ireward/wago/circuitbreaker"
+)
+
+// Breaker is a Strategy executing calls through a circuit breaker.
+type Breaker struct {
+	cb *circuitbreaker.CircuitBreaker
+
+	calls    int64
+	rejected int64
+}
+
+// BreakerStats are the statistics of a Breaker strategy.
+type BreakerStats struct {
+	// Calls is the number of calls that were executed.
+	Calls int64
+	// Rejected is the number of calls rejected by the circuit breaker.
+	Rejected int64
+	// State is the current state of the circuit breaker.
+	State circuitbreaker.State
+}
+
+// NewBreaker creates a Breaker strategy using the given circuit breaker. Calls rejected by the circuit breaker fail
+// with circuitbreaker.ErrOpen or circuitbreaker.ErrTooManyCalls. State changes can be observed with
+// circuitbreaker.OnStateChange.
+func NewBreaker(cb *circuitbreaker.CircuitBreaker) *Breaker {
+	return &Breaker{cb: cb}
+}
+
+// Stats returns the current statistics of the strategy.
+func (b *Breaker) Stats() BreakerStats {
+	return BreakerStats{
+		Calls:    atomic.LoadInt64(&b.calls),
+		Rejected: atomic.LoadInt64(&b.rejected),
+		State:    b.cb.State(),
+	}
+}
+
+func (b *Breaker) order() int {
+	return breakerOrder
+}
+
+func (b *Breaker) execute(ctx context.Context, next handler) (interface{}, error) {
+	var (
+		v      interface{}
+		called bool
+	)
+	err := b.cb.Execute(ctx, func(ctx context.Context) error {
+		called = true
+		atomic.AddInt64(&b.calls, 1)
+		var err error
+		v, err = next(ctx)
+		return err
+	})
+	if !called && (errors.Is(err, circuitbreaker.ErrOpen) || errors.Is(err, circuitbreaker.ErrTooManyCalls)) {
+		atomic.AddInt64(&b.rejected, 1)
+	}
+	return v, err
+}
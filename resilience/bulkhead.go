@@ -0,0 +1,91 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrBulkheadFull is returned by a Bulkhead strategy if neither a call slot nor a queue slot is free.
+var ErrBulkheadFull = errors.New("resilience: bulkhead is full")
+
+// Bulkhead is a Strategy limiting the number of concurrent calls.
+type Bulkhead struct {
+	slots    chan struct{}
+	maxQueue int64
+	onReject []func()
+
+	calls    int64
+	rejected int64
+	queued   int64
+}
+
+// BulkheadStats are the statistics of a Bulkhead strategy.
+type BulkheadStats struct {
+	// Calls is the number of calls that were executed.
+	Calls int64
+	// Rejected is the number of calls rejected with ErrBulkheadFull.
+	Rejected int64
+	// Active is the number of calls currently running.
+	Active int
+	// Queued is the number of calls currently waiting for a free slot.
+	Queued int64
+}
+
+// NewBulkhead creates a Bulkhead strategy running at most maxConcurrent calls at the same time. At most maxQueue
+// further calls wait for a free slot, other calls are rejected with ErrBulkheadFull. The functions given with
+// onReject are called for every rejected call.
+func NewBulkhead(maxConcurrent, maxQueue int, onReject ...func()) *Bulkhead {
+	return &Bulkhead{
+		slots:    make(chan struct{}, maxConcurrent),
+		maxQueue: int64(maxQueue),
+		onReject: onReject,
+	}
+}
+
+// Stats returns the current statistics of the strategy.
+func (b *Bulkhead) Stats() BulkheadStats {
+	return BulkheadStats{
+		Calls:    atomic.LoadInt64(&b.calls),
+		Rejected: atomic.LoadInt64(&b.rejected),
+		Active:   len(b.slots),
+		Queued:   atomic.LoadInt64(&b.queued),
+	}
+}
+
+func (b *Bulkhead) order() int {
+	return bulkheadOrder
+}
+
+func (b *Bulkhead) execute(ctx context.Context, next handler) (interface{}, error) {
+	if err := b.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer func() { <-b.slots }()
+	atomic.AddInt64(&b.calls, 1)
+	return next(ctx)
+}
+
+// acquire takes a slot, waiting in the queue if there is room.
+func (b *Bulkhead) acquire(ctx context.Context) error {
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if atomic.AddInt64(&b.queued, 1) > b.maxQueue {
+		atomic.AddInt64(&b.queued, -1)
+		atomic.AddInt64(&b.rejected, 1)
+		for _, f := range b.onReject {
+			f()
+		}
+		return ErrBulkheadFull
+	}
+	defer atomic.AddInt64(&b.queued, -1)
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,70 @@
+package resilience
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Fallback is a Strategy replacing failed executions with a fallback value of type T.
+type Fallback[T any] struct {
+	fallback   func(ctx context.Context, err error) (T, error)
+	onFallback []func(err error)
+
+	executions int64
+	fallbacks  int64
+}
+
+// FallbackStats are the statistics of a Fallback strategy.
+type FallbackStats struct {
+	// Executions is the number of executions of the strategy.
+	Executions int64
+	// Fallbacks is the number of executions that failed and used the fallback.
+	Fallbacks int64
+}
+
+// NewFallback creates a Fallback strategy calling f with the error of a failed execution. The result of f is returned
+// instead, so f can also return an error to not fall back. The functions given with onFallback are called with the
+// error before f.
+func NewFallback[T any](f func(ctx context.Context, err error) (T, error), onFallback ...func(err error)) *Fallback[T] {
+	return &Fallback[T]{
+		fallback:   f,
+		onFallback: onFallback,
+	}
+}
+
+// FallbackValue creates a Fallback strategy returning v if an execution fails.
+func FallbackValue[T any](v T, onFallback ...func(err error)) *Fallback[T] {
+	return NewFallback(func(context.Context, error) (T, error) {
+		return v, nil
+	}, onFallback...)
+}
+
+// Stats returns the current statistics of the strategy.
+func (f *Fallback[T]) Stats() FallbackStats {
+	return FallbackStats{
+		Executions: atomic.LoadInt64(&f.executions),
+		Fallbacks:  atomic.LoadInt64(&f.fallbacks),
+	}
+}
+
+func (f *Fallback[T]) order() int {
+	return fallbackOrder
+}
+
+func (f *Fallback[T]) produces(ptr interface{}) bool {
+	_, ok := ptr.(*T)
+	return ok
+}
+
+func (f *Fallback[T]) execute(ctx context.Context, next handler) (interface{}, error) {
+	atomic.AddInt64(&f.executions, 1)
+	v, err := next(ctx)
+	if err == nil {
+		return v, nil
+	}
+	atomic.AddInt64(&f.fallbacks, 1)
+	for _, h := range f.onFallback {
+		h(err)
+	}
+	return f.fallback(ctx, err)
+}
@@ -0,0 +1,83 @@
+// Package resilience composes resilience strategies into a Pipeline. The strategies are applied in a fixed order,
+// regardless of the order in which they are given, from the outermost to the innermost:
+//
+//   - Fallback replaces the error of the whole execution with a fallback value.
+//   - Retry retries failed calls according to a backoff policy.
+//   - Breaker rejects calls while a circuit breaker is open.
+//   - Bulkhead limits the number of concurrent calls.
+//   - Timeout bounds the duration of every single call.
+//
+// Thus every retried call passes the circuit breaker and the bulkhead again and gets its own timeout, while the
+// fallback is only used after all retries failed.
+package resilience
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// handler executes an operation or the remaining strategies of a pipeline.
+type handler func(ctx context.Context) (interface{}, error)
+
+// Strategy is a resilience strategy that can be composed into a Pipeline.
+type Strategy interface {
+	// order returns the position of the strategy in a pipeline, lower values are applied first.
+	order() int
+
+	// execute executes next according to the strategy.
+	execute(ctx context.Context, next handler) (interface{}, error)
+}
+
+const (
+	fallbackOrder = iota
+	retryOrder
+	breakerOrder
+	bulkheadOrder
+	timeoutOrder
+)
+
+// Pipeline executes operations returning values of type T through a set of strategies. It is safe for concurrent
+// use, as are the strategies, which can be shared by multiple pipelines.
+type Pipeline[T any] struct {
+	strategies []Strategy
+}
+
+// typedStrategy is implemented by strategies producing values of a certain type, such as Fallback.
+type typedStrategy interface {
+	// produces reports whether the strategy produces values of type T, given a *T.
+	produces(ptr interface{}) bool
+}
+
+// New creates a Pipeline applying the given strategies in the order described in the package documentation.
+// Strategies of the same kind are applied in the given order. New panics if a Fallback is not of the same type T as
+// the pipeline.
+func New[T any](strategies ...Strategy) *Pipeline[T] {
+	s := make([]Strategy, len(strategies))
+	copy(s, strategies)
+	for _, strategy := range s {
+		if typed, ok := strategy.(typedStrategy); ok && !typed.produces((*T)(nil)) {
+			panic(fmt.Sprintf("resilience: %T cannot be used in a Pipeline[%T]", strategy, *new(T)))
+		}
+	}
+	sort.SliceStable(s, func(i, j int) bool {
+		return s[i].order() < s[j].order()
+	})
+	return &Pipeline[T]{strategies: s}
+}
+
+// Execute calls f through the strategies of the pipeline and returns its result.
+func (p *Pipeline[T]) Execute(ctx context.Context, f func(ctx context.Context) (T, error)) (T, error) {
+	next := handler(func(ctx context.Context) (interface{}, error) {
+		return f(ctx)
+	})
+	for i := len(p.strategies) - 1; i >= 0; i-- {
+		s, inner := p.strategies[i], next
+		next = func(ctx context.Context) (interface{}, error) {
+			return s.execute(ctx, inner)
+		}
+	}
+	v, err := next(ctx)
+	t, _ := v.(T)
+	return t, err
+}
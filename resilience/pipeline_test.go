@@ -0,0 +1,236 @@
+package resilience_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+	"github.com/ireward/wago/circuitbreaker"
+	"github.com/ireward/wago/resilience"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errTest = errors.New("test")
+
+func TestPipeline(t *testing.T) {
+	p := resilience.New[int]()
+
+	v, err := p.Execute(context.Background(), func(context.Context) (int, error) {
+		return 1, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	v, err = p.Execute(context.Background(), func(context.Context) (int, error) {
+		return 2, errTest
+	})
+	assert.Equal(t, errTest, err)
+	assert.Equal(t, 2, v)
+}
+
+func TestPipelineOrder(t *testing.T) {
+	var rejected int
+	retry := resilience.NewRetry(backoff.ZeroBackOff().With(backoff.MaxRetries(2)))
+	timeout := resilience.NewTimeout(10 * time.Millisecond)
+	fallback := resilience.FallbackValue("fallback")
+	bulkhead := resilience.NewBulkhead(1, 0, func() { rejected++ })
+
+	// The strategies are given in the wrong order on purpose.
+	p := resilience.New[string](timeout, bulkhead, retry, fallback)
+
+	var count int32
+	v, err := p.Execute(context.Background(), func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&count, 1)
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "fallback", v)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&count))
+
+	assert.Equal(t, resilience.RetryStats{Executions: 1, Attempts: 3, Failures: 1}, retry.Stats())
+	assert.Equal(t, resilience.TimeoutStats{Calls: 3, Timeouts: 3}, timeout.Stats())
+	assert.Equal(t, resilience.FallbackStats{Executions: 1, Fallbacks: 1}, fallback.Stats())
+	assert.Equal(t, resilience.BulkheadStats{Calls: 3}, bulkhead.Stats())
+	assert.Equal(t, 0, rejected)
+}
+
+func TestRetry(t *testing.T) {
+	var notified []int
+	retry := resilience.NewRetry(backoff.ZeroBackOff(), backoff.OnRetry(func(attempt int, err error, next time.Duration) {
+		notified = append(notified, attempt)
+	}))
+	p := resilience.New[int](retry)
+
+	var count int
+	v, err := p.Execute(context.Background(), func(context.Context) (int, error) {
+		count++
+		if count < 3 {
+			return 0, errTest
+		}
+		return count, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+	assert.Equal(t, []int{1, 2}, notified)
+	assert.Equal(t, resilience.RetryStats{Executions: 1, Attempts: 3, Successes: 1}, retry.Stats())
+}
+
+func TestRetryOnResult(t *testing.T) {
+	var count int
+	pipeline := resilience.New[string](resilience.NewRetry(backoff.ZeroBackOff(),
+		backoff.RetryOnResult(func(status string) bool {
+			return status == "pending"
+		})))
+	v, err := pipeline.Execute(context.Background(), func(context.Context) (string, error) {
+		count++
+		if count < 3 {
+			return "pending", nil
+		}
+		return "done", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "done", v)
+	assert.Equal(t, 3, count)
+}
+
+func TestTimeout(t *testing.T) {
+	var timeouts []time.Duration
+	timeout := resilience.NewTimeout(10*time.Millisecond, func(d time.Duration) {
+		timeouts = append(timeouts, d)
+	})
+	p := resilience.New[int](timeout)
+
+	_, err := p.Execute(context.Background(), func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	assert.Equal(t, resilience.ErrTimeout, err)
+	assert.Equal(t, []time.Duration{10 * time.Millisecond}, timeouts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = p.Execute(ctx, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, resilience.TimeoutStats{Calls: 2, Timeouts: 1}, timeout.Stats())
+}
+
+func TestBulkhead(t *testing.T) {
+	var rejected int
+	bulkhead := resilience.NewBulkhead(1, 1, func() { rejected++ })
+	p := resilience.New[int](bulkhead)
+
+	var (
+		wg      sync.WaitGroup
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, err := p.Execute(context.Background(), func(context.Context) (int, error) {
+			close(started)
+			<-release
+			return 0, nil
+		})
+		assert.NoError(t, err)
+	}()
+	<-started
+	go func() {
+		defer wg.Done()
+		_, err := p.Execute(context.Background(), func(context.Context) (int, error) {
+			return 0, nil
+		})
+		assert.NoError(t, err)
+	}()
+	for bulkhead.Stats().Queued == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, resilience.BulkheadStats{Calls: 1, Active: 1, Queued: 1}, bulkhead.Stats())
+
+	_, err := p.Execute(context.Background(), func(context.Context) (int, error) {
+		return 0, nil
+	})
+	assert.Equal(t, resilience.ErrBulkheadFull, err)
+	assert.Equal(t, 1, rejected)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, resilience.BulkheadStats{Calls: 2, Rejected: 1}, bulkhead.Stats())
+}
+
+func TestBreaker(t *testing.T) {
+	breaker := resilience.NewBreaker(circuitbreaker.New(circuitbreaker.FailureThreshold(2)))
+	p := resilience.New[int](breaker)
+
+	for i := 0; i < 3; i++ {
+		_, err := p.Execute(context.Background(), func(context.Context) (int, error) {
+			return 0, errTest
+		})
+		if i < 2 {
+			assert.Equal(t, errTest, err)
+		} else {
+			assert.ErrorIs(t, err, circuitbreaker.ErrOpen)
+		}
+	}
+	assert.Equal(t, resilience.BreakerStats{Calls: 2, Rejected: 1, State: circuitbreaker.Open}, breaker.Stats())
+}
+
+func TestFallback(t *testing.T) {
+	var fallbackErrs []error
+	fallback := resilience.NewFallback(func(ctx context.Context, err error) (int, error) {
+		if errors.Is(err, context.Canceled) {
+			return 0, err
+		}
+		return -1, nil
+	}, func(err error) {
+		fallbackErrs = append(fallbackErrs, err)
+	})
+	p := resilience.New[int](fallback)
+
+	v, err := p.Execute(context.Background(), func(context.Context) (int, error) {
+		return 1, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	v, err = p.Execute(context.Background(), func(context.Context) (int, error) {
+		return 0, errTest
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, -1, v)
+
+	_, err = p.Execute(context.Background(), func(context.Context) (int, error) {
+		return 0, context.Canceled
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, []error{errTest, context.Canceled}, fallbackErrs)
+	assert.Equal(t, resilience.FallbackStats{Executions: 3, Fallbacks: 2}, fallback.Stats())
+}
+
+func TestPipelineFallbackType(t *testing.T) {
+	assert.PanicsWithValue(t, "resilience: *resilience.Fallback[string] cannot be used in a Pipeline[int]", func() {
+		resilience.New[int](resilience.FallbackValue("x"))
+	})
+	assert.NotPanics(t, func() {
+		resilience.New[error](resilience.FallbackValue[error](nil))
+	})
+}
+
+func TestTimeoutResult(t *testing.T) {
+	p := resilience.New[context.Context](resilience.NewTimeout(time.Second))
+
+	ctx, err := p.Execute(context.Background(), func(ctx context.Context) (context.Context, error) {
+		return ctx, nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, ctx.Err())
+}
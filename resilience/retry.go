@@ -0,0 +1,68 @@
+package resilience
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/ireward/wago/backoff"
+)
+
+// Retry is a Strategy retrying failed calls with backoff.RetryContext.
+type Retry struct {
+	policy backoff.Policy
+	opts   []backoff.RetryOption
+
+	executions int64
+	attempts   int64
+	successes  int64
+	failures   int64
+}
+
+// RetryStats are the statistics of a Retry strategy.
+type RetryStats struct {
+	// Executions is the number of executions of the strategy.
+	Executions int64
+	// Attempts is the number of calls including the retries.
+	Attempts int64
+	// Successes is the number of executions that eventually succeeded.
+	Successes int64
+	// Failures is the number of executions that failed after giving up.
+	Failures int64
+}
+
+// NewRetry creates a Retry strategy using the given backoff policy. The options are passed to backoff.RetryContext,
+// so backoff.OnRetry can be used to be notified about retries.
+func NewRetry(p backoff.Policy, opts ...backoff.RetryOption) *Retry {
+	return &Retry{
+		policy: p,
+		opts:   opts,
+	}
+}
+
+// Stats returns the current statistics of the strategy.
+func (r *Retry) Stats() RetryStats {
+	return RetryStats{
+		Executions: atomic.LoadInt64(&r.executions),
+		Attempts:   atomic.LoadInt64(&r.attempts),
+		Successes:  atomic.LoadInt64(&r.successes),
+		Failures:   atomic.LoadInt64(&r.failures),
+	}
+}
+
+func (r *Retry) order() int {
+	return retryOrder
+}
+
+func (r *Retry) execute(ctx context.Context, next handler) (interface{}, error) {
+	atomic.AddInt64(&r.executions, 1)
+	v, err := backoff.RetryWithDataContext(ctx, r.policy, func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt64(&r.attempts, 1)
+		return next(ctx)
+	}, r.opts...)
+	if err != nil {
+		atomic.AddInt64(&r.failures, 1)
+	} else {
+		atomic.AddInt64(&r.successes, 1)
+	}
+	return v, err
+}
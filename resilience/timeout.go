@@ -0,0 +1,82 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+)
+
+// ErrTimeout is returned by a Timeout strategy if a call does not return in time. It is backoff.ErrAttemptTimeout.
+var ErrTimeout = backoff.ErrAttemptTimeout
+
+// once is a backoff policy making a single attempt, used to run calls with backoff.AttemptTimeout.
+var once = backoff.ZeroBackOff().With(backoff.MaxRetries(0))
+
+// Timeout is a Strategy bounding the duration of every call.
+type Timeout struct {
+	timeout   time.Duration
+	onTimeout []func(timeout time.Duration)
+
+	calls    int64
+	timeouts int64
+}
+
+// TimeoutStats are the statistics of a Timeout strategy.
+type TimeoutStats struct {
+	// Calls is the number of calls.
+	Calls int64
+	// Timeouts is the number of calls that timed out.
+	Timeouts int64
+}
+
+// NewTimeout creates a Timeout strategy. Every call is run with backoff.AttemptTimeout, so it receives a context that
+// is done if the call is still running after d. If the call does not return in time, it is abandoned and ErrTimeout is
+// returned. Abandoned calls continue to run in the background until they return, so they must respect the context.
+// The context of a successful call stays usable, e.g. for reading a response body. The functions given with onTimeout
+// are called for every timeout.
+func NewTimeout(d time.Duration, onTimeout ...func(timeout time.Duration)) *Timeout {
+	return &Timeout{
+		timeout:   d,
+		onTimeout: onTimeout,
+	}
+}
+
+// Stats returns the current statistics of the strategy.
+func (t *Timeout) Stats() TimeoutStats {
+	return TimeoutStats{
+		Calls:    atomic.LoadInt64(&t.calls),
+		Timeouts: atomic.LoadInt64(&t.timeouts),
+	}
+}
+
+func (t *Timeout) order() int {
+	return timeoutOrder
+}
+
+func (t *Timeout) execute(ctx context.Context, next handler) (interface{}, error) {
+	atomic.AddInt64(&t.calls, 1)
+
+	// The error of the call is wrapped with backoff.Permanent, so that it is returned unchanged.
+	v, err := backoff.RetryWithDataContext(ctx, once, func(ctx context.Context) (interface{}, error) {
+		v, err := next(ctx)
+		if err != nil {
+			return v, backoff.Permanent(err)
+		}
+		return v, nil
+	}, backoff.AttemptTimeout(t.timeout))
+
+	var retryErr *backoff.RetryError
+	if errors.As(err, &retryErr) {
+		err = retryErr.Err
+	}
+	if err == ErrTimeout {
+		atomic.AddInt64(&t.timeouts, 1)
+		for _, f := range t.onTimeout {
+			f(t.timeout)
+		}
+	}
+	return v, err
+}
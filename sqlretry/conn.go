@@ -0,0 +1,208 @@
+package sqlretry
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+)
+
+// conn is a connection that replaces its underlying connection after transient errors. database/sql uses a
+// connection from one goroutine at a time, so no locking is needed.
+type conn struct {
+	connector *Connector
+	base      driver.Conn
+
+	// generation is incremented with every new underlying connection, so that statements know when to prepare
+	// themselves again.
+	generation int
+	inTx       bool
+}
+
+var (
+	_ driver.Conn               = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.Pinger             = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+	_ driver.SessionResetter    = (*conn)(nil)
+)
+
+// connect creates a new underlying connection if there is none.
+func (c *conn) connect(ctx context.Context) error {
+	if c.base != nil {
+		return nil
+	}
+	base, err := c.connector.base.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	c.base = base
+	c.generation++
+	return nil
+}
+
+// do calls f with the underlying connection, retrying transient errors if no transaction is active. Unless
+// idempotent is set, only driver.ErrBadConn is retried, since f has not been executed then. After a transient error,
+// the underlying connection is closed and replaced on the next call.
+func (c *conn) do(ctx context.Context, idempotent bool, f func(ctx context.Context, base driver.Conn) error) error {
+	var connectFailed bool
+	isTransient := func(err error) bool {
+		if connectFailed {
+			return c.connector.isConnectTransient(err)
+		}
+		return c.connector.isTransient(err) && (idempotent || errors.Is(err, driver.ErrBadConn))
+	}
+	return c.connector.retry(ctx, !c.inTx, isTransient, func(ctx context.Context) error {
+		connectFailed = false
+		if c.base == nil {
+			if c.inTx {
+				return driver.ErrBadConn
+			}
+			if err := c.connect(ctx); err != nil {
+				connectFailed = true
+				return err
+			}
+		}
+		err := f(ctx, c.base)
+		if err != nil && c.connector.isTransient(err) {
+			c.discard()
+		}
+		return err
+	})
+}
+
+// discard closes the underlying connection.
+func (c *conn) discard() {
+	if c.base != nil {
+		_ = c.base.Close()
+		c.base = nil
+	}
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	s := &stmt{conn: c, query: query}
+	if err := c.do(ctx, true, s.prepare); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (c *conn) Close() error {
+	if c.base == nil {
+		return nil
+	}
+	err := c.base.Close()
+	c.base = nil
+	return err
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx starts a transaction, retrying transient errors. Until the transaction ends, no operations are retried.
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var baseTx driver.Tx
+	err := c.do(ctx, true, func(ctx context.Context, base driver.Conn) (err error) {
+		if b, ok := base.(driver.ConnBeginTx); ok {
+			baseTx, err = b.BeginTx(ctx, opts)
+			return err
+		}
+		if opts.Isolation != driver.IsolationLevel(0) || opts.ReadOnly {
+			return errors.New("sqlretry: driver does not support transaction options")
+		}
+		baseTx, err = base.Begin()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.inTx = true
+	return &tx{conn: c, base: baseTx}, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	var rows driver.Rows
+	err := c.do(ctx, c.connector.isIdempotent(query, false), func(ctx context.Context, base driver.Conn) (err error) {
+		q, ok := base.(driver.QueryerContext)
+		if !ok {
+			return driver.ErrSkip
+		}
+		rows, err = q.QueryContext(ctx, query, args)
+		return err
+	})
+	return rows, err
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	var result driver.Result
+	err := c.do(ctx, c.connector.isIdempotent(query, true), func(ctx context.Context, base driver.Conn) (err error) {
+		e, ok := base.(driver.ExecerContext)
+		if !ok {
+			return driver.ErrSkip
+		}
+		result, err = e.ExecContext(ctx, query, args)
+		return err
+	})
+	return result, err
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	return c.do(ctx, true, func(ctx context.Context, base driver.Conn) error {
+		if p, ok := base.(driver.Pinger); ok {
+			return p.Ping(ctx)
+		}
+		return nil
+	})
+}
+
+// CheckNamedValue lets the underlying connection convert arguments. Without an underlying connection, the default
+// conversion of database/sql is used.
+func (c *conn) CheckNamedValue(v *driver.NamedValue) error {
+	if checker, ok := c.base.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.base.(driver.SessionResetter); ok {
+		if err := r.ResetSession(ctx); err != nil {
+			if c.connector.isTransient(err) {
+				c.discard()
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// tx is a transaction of a conn. It allows retries again once the transaction ends.
+type tx struct {
+	conn *conn
+	base driver.Tx
+}
+
+func (t *tx) Commit() error {
+	t.conn.inTx = false
+	return t.end(t.base.Commit())
+}
+
+func (t *tx) Rollback() error {
+	t.conn.inTx = false
+	return t.end(t.base.Rollback())
+}
+
+func (t *tx) end(err error) error {
+	if err != nil && t.conn.connector.isTransient(err) {
+		t.conn.discard()
+	}
+	return err
+}
@@ -0,0 +1,170 @@
+package sqlretry_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"sync"
+)
+
+var errSyntax = errors.New("syntax error")
+
+// fakeServer is an in-process database. Connections break when it is told to fail statements. Like real drivers,
+// broken connections return driver.ErrBadConn before executing anything.
+type fakeServer struct {
+	mu           sync.Mutex
+	failConnects int
+	failStmts    int
+	failErr      error
+	connects     int
+	prepares     int
+	attempts     int
+	executed     []string
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{failErr: driver.ErrBadConn}
+}
+
+func (s *fakeServer) Connect(context.Context) (driver.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connects++
+	if s.failConnects > 0 {
+		s.failConnects--
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	}
+	return &fakeConn{server: s}, nil
+}
+
+func (s *fakeServer) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+func (s *fakeServer) exec(c *fakeConn, query string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if c.broken {
+		return driver.ErrBadConn
+	}
+	if s.failStmts > 0 {
+		s.failStmts--
+		c.broken = true
+		return s.failErr
+	}
+	if query == "FAIL" {
+		return errSyntax
+	}
+	s.executed = append(s.executed, query)
+	return nil
+}
+
+func (s *fakeServer) stats() (connects, prepares, attempts int, executed []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connects, s.prepares, s.attempts, append([]string(nil), s.executed...)
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("fake: use the connector")
+}
+
+type fakeConn struct {
+	server *fakeServer
+	broken bool
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.server.mu.Lock()
+	c.server.prepares++
+	c.server.mu.Unlock()
+	if c.broken {
+		return nil, driver.ErrBadConn
+	}
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	if err := c.server.exec(c, "BEGIN"); err != nil {
+		return nil, err
+	}
+	return &fakeTx{conn: c}, nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if err := c.server.exec(c, query); err != nil {
+		return nil, err
+	}
+	return &fakeRows{}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if err := c.server.exec(c, query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct {
+	conn *fakeConn
+}
+
+func (t *fakeTx) Commit() error {
+	return t.conn.server.exec(t.conn, "COMMIT")
+}
+
+func (t *fakeTx) Rollback() error {
+	return t.conn.server.exec(t.conn, "ROLLBACK")
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, nil)
+}
+
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, nil)
+}
+
+// fakeRows returns a single row with the value 1.
+type fakeRows struct {
+	done bool
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"n"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
@@ -0,0 +1,56 @@
+package sqlretry
+
+import (
+	"database/sql/driver"
+	"errors"
+
+	"github.com/ireward/wago/backoff"
+)
+
+// An Option configures a Connector.
+type Option interface {
+	apply(c *Connector)
+}
+
+// optionFunc wraps a func so it satisfies the Option interface.
+type optionFunc func(*Connector)
+
+func (f optionFunc) apply(c *Connector) {
+	f(c)
+}
+
+// IsTransient configures which errors of the driver are transient, i.e. are expected to go away after reconnecting.
+// Only transient errors are retried. By default, only driver.ErrBadConn is transient, which drivers return only if
+// the statement has not been executed. Errors such as unexpected ends of the connection may occur after the server
+// executed the statement, so they should only be treated as transient together with IsIdempotent.
+// Connecting is additionally retried on network errors.
+func IsTransient(isTransient func(err error) bool) Option {
+	return optionFunc(func(c *Connector) {
+		c.isTransient = isTransient
+	})
+}
+
+// IsIdempotent configures which statements may be re-run after a transient error other than driver.ErrBadConn. The
+// function is called with the query and whether it is executed with Exec. By default, no statements are re-run, since
+// queries can modify data as well, e.g. INSERT ... RETURNING. Statements failing with driver.ErrBadConn have not been
+// executed and are always retried.
+func IsIdempotent(isIdempotent func(query string, exec bool) bool) Option {
+	return optionFunc(func(c *Connector) {
+		c.isIdempotent = isIdempotent
+	})
+}
+
+// RetryOptions configures the options given to backoff.RetryContext, e.g. backoff.OnRetry to log retries.
+func RetryOptions(opts ...backoff.RetryOption) Option {
+	return optionFunc(func(c *Connector) {
+		c.retryOpts = append(c.retryOpts, opts...)
+	})
+}
+
+func defaultIsTransient(err error) bool {
+	return errors.Is(err, driver.ErrBadConn)
+}
+
+func defaultIsIdempotent(string, bool) bool {
+	return false
+}
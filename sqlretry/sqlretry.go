@@ -0,0 +1,121 @@
+// Package sqlretry wraps database/sql drivers to retry operations failing with transient errors, e.g. during a
+// database failover. Broken connections are replaced by new ones, and statements that were not executed are re-run
+// according to a backoff policy. Statements within a transaction are never retried.
+package sqlretry
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/ireward/wago/backoff"
+)
+
+// Connector is a driver.Connector retrying operations of the connections created by another connector.
+type Connector struct {
+	base         driver.Connector
+	policy       backoff.Policy
+	isTransient  func(err error) bool
+	isIdempotent func(query string, exec bool) bool
+	retryOpts    []backoff.RetryOption
+}
+
+var _ driver.Connector = (*Connector)(nil)
+
+// NewConnector creates a Connector wrapping base. Connecting, operations failing with driver.ErrBadConn and idempotent
+// operations failing with a transient error are retried according to the backoff policy p.
+func NewConnector(base driver.Connector, p backoff.Policy, opts ...Option) *Connector {
+	c := &Connector{
+		base:         base,
+		policy:       p,
+		isTransient:  defaultIsTransient,
+		isIdempotent: defaultIsIdempotent,
+	}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	return c
+}
+
+// OpenDB opens a database using a Connector wrapping base.
+func OpenDB(base driver.Connector, p backoff.Policy, opts ...Option) *sql.DB {
+	return sql.OpenDB(NewConnector(base, p, opts...))
+}
+
+// Connect creates a connection, retrying transient errors and network errors.
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	cn := &conn{connector: c}
+	if err := c.retry(ctx, true, c.isConnectTransient, func(ctx context.Context) error {
+		return cn.connect(ctx)
+	}); err != nil {
+		return nil, err
+	}
+	return cn, nil
+}
+
+// Driver returns the driver of the wrapped connector.
+func (c *Connector) Driver() driver.Driver {
+	return c.base.Driver()
+}
+
+// isConnectTransient reports whether an error of connecting is transient. Besides the errors configured with
+// IsTransient, network errors other than context errors are transient, since no statement can have been executed.
+func (c *Connector) isConnectTransient(err error) bool {
+	if c.isTransient(err) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retry calls f according to the policy if retryable is set and f fails with an error for which isTransient returns
+// true. Otherwise, f is called once. Errors that are not transient are returned as they are, because database/sql
+// compares some of them, such as driver.ErrSkip, by identity.
+func (c *Connector) retry(ctx context.Context, retryable bool, isTransient func(err error) bool,
+	f func(ctx context.Context) error) error {
+	if !retryable {
+		return f(ctx)
+	}
+	err := backoff.RetryContext(ctx, c.policy, func(ctx context.Context) error {
+		err := f(ctx)
+		if err != nil && !isTransient(err) {
+			return backoff.Permanent(err)
+		}
+		return err
+	}, c.retryOpts...)
+
+	var retryErr *backoff.RetryError
+	if !errors.As(err, &retryErr) {
+		return err
+	}
+	switch {
+	case retryErr.Reason == backoff.StopPermanent:
+		return retryErr.Err
+	case retryErr.Reason != backoff.StopContext && errors.Is(err, driver.ErrBadConn):
+		return &exhaustedError{retryErr: retryErr}
+	}
+	return err
+}
+
+// exhaustedError hides driver.ErrBadConn from database/sql, which would otherwise retry the operation on another
+// connection although the policy is already exhausted. It still matches the RetryError with errors.As.
+type exhaustedError struct {
+	retryErr *backoff.RetryError
+}
+
+func (e *exhaustedError) Error() string {
+	return e.retryErr.Error()
+}
+
+func (e *exhaustedError) As(target interface{}) bool {
+	if t, ok := target.(**backoff.RetryError); ok {
+		*t = e.retryErr
+		return true
+	}
+	return false
+}
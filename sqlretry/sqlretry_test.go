@@ -0,0 +1,202 @@
+package sqlretry_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ireward/wago/backoff"
+	"github.com/ireward/wago/backoff/backofftest"
+	"github.com/ireward/wago/sqlretry"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var policy = backoff.ZeroBackOff().With(backoff.MaxRetries(3))
+
+func queryOne(ctx context.Context, db interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}, query string) error {
+	var n int
+	return db.QueryRowContext(ctx, query).Scan(&n)
+}
+
+func TestConnectRetry(t *testing.T) {
+	server := newFakeServer()
+	server.failConnects = 2
+	db := sqlretry.OpenDB(server, policy)
+	defer db.Close()
+
+	assert.NoError(t, db.PingContext(context.Background()))
+	connects, _, _, _ := server.stats()
+	assert.Equal(t, 3, connects)
+}
+
+func TestQueryRetry(t *testing.T) {
+	server := newFakeServer()
+	db := sqlretry.OpenDB(server, policy)
+	defer db.Close()
+
+	assert.NoError(t, db.PingContext(context.Background()))
+	server.failStmts = 1
+	assert.NoError(t, queryOne(context.Background(), db, "SELECT 1"))
+
+	connects, _, attempts, executed := server.stats()
+	assert.Equal(t, 2, connects)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, []string{"SELECT 1"}, executed)
+}
+
+func TestNotIdempotent(t *testing.T) {
+	server := newFakeServer()
+	server.failErr = io.ErrUnexpectedEOF
+	db := sqlretry.OpenDB(server, policy)
+	defer db.Close()
+
+	// The statements may have been executed before the connection broke.
+	server.failStmts = 1
+	assert.ErrorIs(t, queryOne(context.Background(), db, "INSERT RETURNING"), io.ErrUnexpectedEOF)
+	server.failStmts = 1
+	_, err := db.ExecContext(context.Background(), "INSERT")
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	// The broken connection is replaced by the next operation.
+	_, err = db.ExecContext(context.Background(), "INSERT")
+	assert.NoError(t, err)
+	connects, _, attempts, executed := server.stats()
+	assert.Equal(t, 3, connects)
+	assert.Equal(t, 5, attempts)
+	assert.Equal(t, []string{"INSERT"}, executed)
+}
+
+func TestIsIdempotent(t *testing.T) {
+	server := newFakeServer()
+	server.failErr = io.ErrUnexpectedEOF
+	db := sqlretry.OpenDB(server, policy, sqlretry.IsTransient(func(err error) bool {
+		return errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF)
+	}), sqlretry.IsIdempotent(func(query string, exec bool) bool {
+		return query == "DELETE"
+	}))
+	defer db.Close()
+
+	server.failStmts = 1
+	_, err := db.ExecContext(context.Background(), "DELETE")
+	assert.NoError(t, err)
+
+	server.failStmts = 1
+	assert.ErrorIs(t, queryOne(context.Background(), db, "SELECT 1"), io.ErrUnexpectedEOF)
+}
+
+func TestIsTransient(t *testing.T) {
+	server := newFakeServer()
+	server.failErr = io.ErrUnexpectedEOF
+	db := sqlretry.OpenDB(server, policy, sqlretry.IsTransient(func(err error) bool {
+		return errors.Is(err, errSyntax)
+	}), sqlretry.IsIdempotent(func(string, bool) bool {
+		return true
+	}))
+	defer db.Close()
+
+	var retryErr *backoff.RetryError
+	err := queryOne(context.Background(), db, "FAIL")
+	if assert.ErrorAs(t, err, &retryErr) {
+		assert.Equal(t, 4, retryErr.Attempts)
+	}
+
+	server.failStmts = 1
+	assert.ErrorIs(t, queryOne(context.Background(), db, "SELECT 1"), io.ErrUnexpectedEOF)
+}
+
+func TestPermanentError(t *testing.T) {
+	server := newFakeServer()
+	db := sqlretry.OpenDB(server, policy)
+	defer db.Close()
+
+	assert.Equal(t, errSyntax, queryOne(context.Background(), db, "FAIL"))
+	_, _, attempts, _ := server.stats()
+	assert.Equal(t, 1, attempts)
+}
+
+func TestTransactionNotRetried(t *testing.T) {
+	server := newFakeServer()
+	server.failErr = io.ErrUnexpectedEOF
+	db := sqlretry.OpenDB(server, policy)
+	defer db.Close()
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	assert.NoError(t, err)
+	server.failStmts = 1
+	assert.ErrorIs(t, queryOne(context.Background(), tx, "SELECT 1"), io.ErrUnexpectedEOF)
+	assert.Error(t, tx.Rollback())
+
+	_, _, _, executed := server.stats()
+	assert.Equal(t, []string{"BEGIN"}, executed)
+
+	tx, err = db.BeginTx(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.NoError(t, queryOne(context.Background(), tx, "SELECT 1"))
+	assert.NoError(t, tx.Commit())
+
+	_, _, _, executed = server.stats()
+	assert.Equal(t, []string{"BEGIN", "BEGIN", "SELECT 1", "COMMIT"}, executed)
+}
+
+func TestPreparedStatement(t *testing.T) {
+	server := newFakeServer()
+	db := sqlretry.OpenDB(server, policy)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	stmt, err := db.PrepareContext(context.Background(), "SELECT 1")
+	assert.NoError(t, err)
+	defer stmt.Close()
+
+	server.failStmts = 1
+	var n int
+	assert.NoError(t, stmt.QueryRowContext(context.Background()).Scan(&n))
+
+	connects, prepares, _, executed := server.stats()
+	assert.Equal(t, 2, connects)
+	assert.Equal(t, 2, prepares)
+	assert.Equal(t, []string{"SELECT 1"}, executed)
+}
+
+func TestExhausted(t *testing.T) {
+	server := newFakeServer()
+	db := sqlretry.OpenDB(server, policy)
+	defer db.Close()
+
+	assert.NoError(t, db.PingContext(context.Background()))
+	server.failStmts = 100
+	err := queryOne(context.Background(), db, "SELECT 1")
+
+	var retryErr *backoff.RetryError
+	assert.ErrorAs(t, err, &retryErr)
+	assert.Equal(t, backoff.StopMaxRetries, retryErr.Reason)
+
+	// database/sql does not retry on top of the policy.
+	_, _, attempts, _ := server.stats()
+	assert.Equal(t, 4, attempts)
+}
+
+func TestContext(t *testing.T) {
+	server := newFakeServer()
+	server.failConnects = 100
+	clock := backofftest.NewFakeClock(time.Now())
+	db := sqlretry.OpenDB(server, backoff.ConstantBackOff(time.Hour),
+		sqlretry.RetryOptions(backoff.WithClock(clock)))
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		clock.BlockUntil(1)
+		cancel()
+	}()
+	assert.ErrorIs(t, db.PingContext(ctx), context.Canceled)
+	connects, _, _, _ := server.stats()
+	assert.Equal(t, 1, connects)
+}
@@ -0,0 +1,116 @@
+package sqlretry
+
+import (
+	"context"
+	"database/sql/driver"
+)
+
+// stmt is a prepared statement of a conn. It is prepared again when the underlying connection has been replaced.
+type stmt struct {
+	conn       *conn
+	query      string
+	base       driver.Stmt
+	generation int
+	numInput   int
+}
+
+var (
+	_ driver.Stmt             = (*stmt)(nil)
+	_ driver.StmtExecContext  = (*stmt)(nil)
+	_ driver.StmtQueryContext = (*stmt)(nil)
+)
+
+// prepare prepares the statement on the underlying connection if it is not prepared on it yet.
+func (s *stmt) prepare(ctx context.Context, base driver.Conn) (err error) {
+	if s.base != nil && s.generation == s.conn.generation {
+		return nil
+	}
+	if s.base != nil {
+		_ = s.base.Close()
+		s.base = nil
+	}
+	var baseStmt driver.Stmt
+	if p, ok := base.(driver.ConnPrepareContext); ok {
+		baseStmt, err = p.PrepareContext(ctx, s.query)
+	} else {
+		baseStmt, err = base.Prepare(s.query)
+	}
+	if err != nil {
+		return err
+	}
+	s.base = baseStmt
+	s.generation = s.conn.generation
+	s.numInput = baseStmt.NumInput()
+	return nil
+}
+
+func (s *stmt) Close() error {
+	if s.base == nil {
+		return nil
+	}
+	err := s.base.Close()
+	s.base = nil
+	return err
+}
+
+func (s *stmt) NumInput() int {
+	return s.numInput
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	var result driver.Result
+	err := s.conn.do(ctx, s.conn.connector.isIdempotent(s.query, true), func(ctx context.Context, base driver.Conn) error {
+		if err := s.prepare(ctx, base); err != nil {
+			return err
+		}
+		var err error
+		if e, ok := s.base.(driver.StmtExecContext); ok {
+			result, err = e.ExecContext(ctx, args)
+		} else {
+			result, err = s.base.Exec(values(args))
+		}
+		return err
+	})
+	return result, err
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	var rows driver.Rows
+	err := s.conn.do(ctx, s.conn.connector.isIdempotent(s.query, false), func(ctx context.Context, base driver.Conn) error {
+		if err := s.prepare(ctx, base); err != nil {
+			return err
+		}
+		var err error
+		if q, ok := s.base.(driver.StmtQueryContext); ok {
+			rows, err = q.QueryContext(ctx, args)
+		} else {
+			rows, err = s.base.Query(values(args))
+		}
+		return err
+	})
+	return rows, err
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	vs := make([]driver.Value, len(args))
+	for i, arg := range args {
+		vs[i] = arg.Value
+	}
+	return vs
+}